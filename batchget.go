@@ -0,0 +1,184 @@
+package goddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxBatchGetKeys = 100
+
+// NotFoundError is returned by batch requests when some of the items do not
+// exist. The items that do exist are still loaded.
+type NotFoundError struct {
+	Values []any
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%d of the items not found", len(e.Values))
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrItemNotFound
+}
+
+type BatchGetRequest struct {
+	db          *DB
+	stats       *Stats
+	values      []any
+	consistent  bool
+	concurrency int
+}
+
+// BatchGet loads the items with the keys of the given struct pointers, which
+// may be of different types, into them.
+func BatchGet(values ...any) *BatchGetRequest {
+	return &BatchGetRequest{
+		values:      values,
+		concurrency: 4,
+	}
+}
+
+func (r *BatchGetRequest) Consistent() *BatchGetRequest {
+	r.consistent = true
+	return r
+}
+
+// Concurrency sets the maximum number of calls made at once. Defaults to 4.
+func (r *BatchGetRequest) Concurrency(n int) *BatchGetRequest {
+	r.concurrency = n
+	return r
+}
+
+func (r *BatchGetRequest) In(db *DB) *BatchGetRequest {
+	r.db = db
+	return r
+}
+
+func (r *BatchGetRequest) Stats(s *Stats) *BatchGetRequest {
+	r.stats = s
+	return r
+}
+
+func (r *BatchGetRequest) Exec() error {
+	return r.ExecContext(context.Background())
+}
+
+func (r *BatchGetRequest) ExecContext(ctx context.Context) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb batch get: %w", err)
+	}
+	db, err := resolve(r.db)
+	if err != nil {
+		return wrap(err)
+	}
+	ctx, done := db.track(ctx, OperationBatchGet, r.stats)
+	defer done()
+	type key struct {
+		id     string
+		key    map[string]types.AttributeValue
+		values []any
+	}
+	var keys []*key
+	byID := make(map[string]*key)
+	vals := make(map[any]reflect.Value)
+	for _, v := range r.values {
+		if reflect.ValueOf(v).Kind() != reflect.Pointer {
+			return wrap(invalid(errors.New("must be pointer to struct")))
+		}
+		val, err := valueOf(v)
+		if err != nil {
+			return wrap(invalid(err))
+		}
+		item, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+		if err != nil {
+			return wrap(invalid(err))
+		}
+		vals[v] = val
+		id := keyID(item)
+		if k, ok := byID[id]; ok {
+			k.values = append(k.values, v)
+			continue
+		}
+		k := &key{id: id, key: db.toTable(item), values: []any{v}}
+		byID[id] = k
+		keys = append(keys, k)
+	}
+	var mu sync.Mutex
+	found := make(map[string]bool)
+	err = parallel(ctx, r.concurrency, slices.Collect(slices.Chunk(keys, maxBatchGetKeys)), func(ctx context.Context, chunk []*key) error {
+		var values []any
+		input := &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{
+			db.tableName: {ConsistentRead: aws.Bool(r.consistent)},
+		}}
+		for _, k := range chunk {
+			values = append(values, k.values...)
+			ka := input.RequestItems[db.tableName]
+			ka.Keys = append(ka.Keys, k.key)
+			input.RequestItems[db.tableName] = ka
+		}
+		items, err := r.get(ctx, db, values, input)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range items {
+			item = db.fromTable(item)
+			k, ok := byID[keyID(item)]
+			if !ok {
+				continue
+			}
+			found[k.id] = true
+			for _, v := range k.values {
+				if err := setFieldValues(vals[v], item, db.tagChar); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	var missing []any
+	for _, k := range keys {
+		if !found[k.id] {
+			missing = append(missing, k.values...)
+		}
+	}
+	if len(missing) > 0 {
+		return wrap(&NotFoundError{Values: missing})
+	}
+	return nil
+}
+
+// get sends input, retrying unprocessed keys, and returns the items found.
+func (r *BatchGetRequest) get(ctx context.Context, db *DB, values []any, input *dynamodb.BatchGetItemInput) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for attempt := 0; ; attempt++ {
+		output, err := send[dynamodb.BatchGetItemOutput](ctx, db, OperationBatchGet, values, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, output.Responses[db.tableName]...)
+		unprocessed := len(output.UnprocessedKeys[db.tableName].Keys)
+		if unprocessed == 0 {
+			return items, nil
+		}
+		if attempt+1 >= db.retry.batchAttempts() {
+			return nil, fmt.Errorf("%d keys unprocessed after %d attempts", unprocessed, attempt+1)
+		}
+		if err := db.retry.wait(ctx, attempt); err != nil {
+			return nil, err
+		}
+		input = &dynamodb.BatchGetItemInput{RequestItems: output.UnprocessedKeys}
+	}
+}
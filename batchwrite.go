@@ -0,0 +1,211 @@
+package goddb
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxBatchWriteItems = 25
+
+// BatchWriteError is returned by BatchWrite when some of the values could
+// not be written.
+type BatchWriteError struct {
+	Failures []WriteFailure
+}
+
+type WriteFailure struct {
+	Value any
+	Err   error
+}
+
+func (e *BatchWriteError) Error() string {
+	return fmt.Sprintf("%d of the items not written: %s", len(e.Failures), e.Failures[0].Err)
+}
+
+func (e *BatchWriteError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+type BatchWriteRequest struct {
+	db          *DB
+	stats       *Stats
+	writes      []batchWrite
+	concurrency int
+}
+
+type batchWrite struct {
+	value  any
+	delete bool
+}
+
+func BatchWrite() *BatchWriteRequest {
+	return &BatchWriteRequest{
+		concurrency: 4,
+	}
+}
+
+func (r *BatchWriteRequest) Put(value any) *BatchWriteRequest {
+	r.writes = append(r.writes, batchWrite{value: value})
+	return r
+}
+
+func (r *BatchWriteRequest) Delete(value any) *BatchWriteRequest {
+	r.writes = append(r.writes, batchWrite{value: value, delete: true})
+	return r
+}
+
+// Concurrency sets the maximum number of calls made at once. Defaults to 4.
+func (r *BatchWriteRequest) Concurrency(n int) *BatchWriteRequest {
+	r.concurrency = n
+	return r
+}
+
+func (r *BatchWriteRequest) In(db *DB) *BatchWriteRequest {
+	r.db = db
+	return r
+}
+
+func (r *BatchWriteRequest) Stats(s *Stats) *BatchWriteRequest {
+	r.stats = s
+	return r
+}
+
+func (r *BatchWriteRequest) Exec() error {
+	return r.ExecContext(context.Background())
+}
+
+func (r *BatchWriteRequest) ExecContext(ctx context.Context) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb batch write: %w", err)
+	}
+	db, err := resolve(r.db)
+	if err != nil {
+		return wrap(err)
+	}
+	ctx, done := db.track(ctx, OperationBatchWrite, r.stats)
+	defer done()
+	// A batch cannot write the same item twice, so only the last write of
+	// an item, in the order Put and Delete were called, is sent.
+	type write struct {
+		req    types.WriteRequest
+		values []any
+	}
+	var writes []*write
+	byID := make(map[string]*write)
+	add := func(value any, attrs func(attr string) bool, req func(item map[string]types.AttributeValue) types.WriteRequest) error {
+		val, err := valueOf(value)
+		if err != nil {
+			return err
+		}
+		ty := val.Type()
+		item, err := makeItem(ty, val, db.tagChar, attrs)
+		if err != nil {
+			return err
+		}
+		if err := validateCompleteKey(ty, val); err != nil {
+			return err
+		}
+		id := keyID(item)
+		w, ok := byID[id]
+		if !ok {
+			w = &write{}
+			byID[id] = w
+			writes = append(writes, w)
+		}
+		w.req = req(db.toTable(item))
+		w.values = append(w.values, value)
+		return nil
+	}
+	for _, w := range r.writes {
+		var err error
+		if w.delete {
+			err = add(w.value, func(attr string) bool { return attr == "SK" || attr == "PK" }, func(item map[string]types.AttributeValue) types.WriteRequest {
+				return types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: item}}
+			})
+		} else {
+			err = add(w.value, func(attr string) bool { return true }, func(item map[string]types.AttributeValue) types.WriteRequest {
+				return types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+			})
+		}
+		if err != nil {
+			return wrap(invalid(err))
+		}
+	}
+	var mu sync.Mutex
+	var failures []WriteFailure
+	err = parallel(ctx, r.concurrency, slices.Collect(slices.Chunk(writes, maxBatchWriteItems)), func(ctx context.Context, chunk []*write) error {
+		var values []any
+		reqs := make([]types.WriteRequest, len(chunk))
+		for i, w := range chunk {
+			values = append(values, w.values...)
+			reqs[i] = w.req
+		}
+		unprocessed, err := r.write(ctx, db, values, reqs)
+		if err == nil {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for i, w := range chunk {
+			if unprocessed == nil || unprocessed[i] {
+				for _, v := range w.values {
+					failures = append(failures, WriteFailure{Value: v, Err: err})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	if len(failures) > 0 {
+		return wrap(&BatchWriteError{Failures: failures})
+	}
+	return nil
+}
+
+// write sends reqs, retrying unprocessed items. If it fails after some items
+// were processed, it reports which of reqs were not.
+func (r *BatchWriteRequest) write(ctx context.Context, db *DB, values []any, reqs []types.WriteRequest) ([]bool, error) {
+	var unprocessed []bool
+	input := &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{db.tableName: reqs}}
+	for attempt := 0; ; attempt++ {
+		output, err := send[dynamodb.BatchWriteItemOutput](ctx, db, OperationBatchWrite, values, input)
+		if err != nil {
+			return unprocessed, err
+		}
+		rest := output.UnprocessedItems[db.tableName]
+		if len(rest) == 0 {
+			return nil, nil
+		}
+		unprocessed = make([]bool, len(reqs))
+		for i, req := range reqs {
+			unprocessed[i] = slices.ContainsFunc(rest, func(u types.WriteRequest) bool {
+				return keyID(db.fromTable(writeKey(req))) == keyID(db.fromTable(writeKey(u)))
+			})
+		}
+		if attempt+1 >= db.retry.batchAttempts() {
+			return unprocessed, fmt.Errorf("items unprocessed after %d attempts", attempt+1)
+		}
+		if err := db.retry.wait(ctx, attempt); err != nil {
+			return unprocessed, err
+		}
+		input = &dynamodb.BatchWriteItemInput{RequestItems: output.UnprocessedItems}
+	}
+}
+
+func writeKey(req types.WriteRequest) map[string]types.AttributeValue {
+	if req.PutRequest != nil {
+		return req.PutRequest.Item
+	}
+	return req.DeleteRequest.Key
+}
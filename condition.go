@@ -1,7 +1,6 @@
 package goddb
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
@@ -21,8 +20,6 @@ const (
 	operatorAttributeNotExists
 )
 
-var ErrConditionFailed = errors.New("condition failed")
-
 type Condition[T any] struct {
 	and      []*Condition[T]
 	or       []*Condition[T]
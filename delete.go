@@ -2,45 +2,122 @@ package goddb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type DeleteRequest[T any] struct {
+	db        *DB
+	stats     *Stats
+	operation Operation
 	value     *T
 	input     *dynamodb.DeleteItemInput
 	condition *Condition[T]
+	returned  *T
+	onFailure *T
 }
 
 func (r *DeleteRequest[T]) Exec() error {
+	return r.ExecContext(context.Background())
+}
+
+func (r *DeleteRequest[T]) ExecContext(ctx context.Context) error {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb delete: %w", err)
 	}
-	val, err := valueOf(r.value)
+	db, err := resolve(r.db)
 	if err != nil {
 		return wrap(err)
 	}
-	r.input.Key, err = makeItem(val.Type(), val, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	ctx, done := db.track(ctx, r.operation, r.stats)
+	defer done()
+	input, err := r.build(db)
 	if err != nil {
+		return wrap(invalid(err))
+	}
+	output, err := send[dynamodb.DeleteItemOutput](ctx, db, r.operation, r.value, input)
+	if err != nil {
+		var ex *types.ConditionalCheckFailedException
+		if errors.As(err, &ex) {
+			if err := decode(db, r.onFailure, ex.Item); err != nil {
+				return wrap(err)
+			}
+		}
 		return wrap(err)
 	}
+	if err := decode(db, r.returned, output.Attributes); err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (r *DeleteRequest[T]) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb delete: %w", err)
+	}
+	db, err := resolve(r.db)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	input, err := r.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: r.operation, Input: input}, nil
+}
+
+func (r *DeleteRequest[T]) build(db *DB) (*dynamodb.DeleteItemInput, error) {
+	val, err := valueOf(r.value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	if err != nil {
+		return nil, err
+	}
+	input := *r.input
+	input.TableName = aws.String(db.tableName)
+	input.Key = db.toTable(key)
 	if r.condition != nil {
-		exp, names, values, err := r.condition.expression(len(r.input.ExpressionAttributeValues))
+		exp, names, values, err := r.condition.expression(len(input.ExpressionAttributeValues))
 		if err != nil {
-			return wrap(err)
+			return nil, err
 		}
-		r.input.ConditionExpression = &exp
-		r.input.ExpressionAttributeNames = merge(r.input.ExpressionAttributeNames, names)
-		r.input.ExpressionAttributeValues = merge(r.input.ExpressionAttributeValues, values)
+		input.ConditionExpression = &exp
+		input.ExpressionAttributeNames = merge(input.ExpressionAttributeNames, names)
+		input.ExpressionAttributeValues = merge(input.ExpressionAttributeValues, values)
+	}
+	return &input, nil
+}
+
+func (r *DeleteRequest[T]) transactionValue() any {
+	return r.value
+}
+
+func (r *DeleteRequest[T]) transactionItem(db *DB) (*types.TransactWriteItem, error) {
+	if err := sameDB(r.db, db); err != nil {
+		return nil, err
 	}
-	_, err = client.DeleteItem(context.Background(), r.input)
+	input, err := r.build(db)
 	if err != nil {
-		return wrap(err)
+		return nil, err
 	}
-	return nil
+	return &types.TransactWriteItem{Delete: &types.Delete{
+		Key:                                 input.Key,
+		TableName:                           input.TableName,
+		ConditionExpression:                 input.ConditionExpression,
+		ExpressionAttributeNames:            input.ExpressionAttributeNames,
+		ExpressionAttributeValues:           input.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+	}}, nil
+}
+
+func (r *DeleteRequest[T]) transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error) {
+	return decodeOld(db, r.onFailure, attrs)
 }
 
 func (r *DeleteRequest[T]) If(condition *Condition[T]) *DeleteRequest[T] {
@@ -48,11 +125,35 @@ func (r *DeleteRequest[T]) If(condition *Condition[T]) *DeleteRequest[T] {
 	return r
 }
 
+// ReturnOld loads the deleted item into v, if there was one.
+func (r *DeleteRequest[T]) ReturnOld(v *T) *DeleteRequest[T] {
+	r.input.ReturnValues = types.ReturnValueAllOld
+	r.returned = v
+	return r
+}
+
+// ReturnOldOnConditionFailure loads the existing item into v if the
+// condition fails.
+func (r *DeleteRequest[T]) ReturnOldOnConditionFailure(v *T) *DeleteRequest[T] {
+	r.input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	r.onFailure = v
+	return r
+}
+
+func (r *DeleteRequest[T]) In(db *DB) *DeleteRequest[T] {
+	r.db = db
+	return r
+}
+
+func (r *DeleteRequest[T]) Stats(s *Stats) *DeleteRequest[T] {
+	r.stats = s
+	return r
+}
+
 func Delete[T any](v *T) *DeleteRequest[T] {
 	return &DeleteRequest[T]{
-		value: v,
-		input: &dynamodb.DeleteItemInput{
-			TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
-		},
+		value:     v,
+		operation: OperationDelete,
+		input:     &dynamodb.DeleteItemInput{},
 	}
 }
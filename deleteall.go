@@ -1,10 +1,13 @@
 package goddb
 
 import (
+	"context"
 	"fmt"
 )
 
 type DeleteAllRequest[T any] struct {
+	db           *DB
+	stats        *Stats
 	value        *T
 	beginsWith   *T
 	betweenStart *T
@@ -28,16 +31,43 @@ func (r *DeleteAllRequest[T]) Between(start *T, end *T) *DeleteAllRequest[T] {
 	return r
 }
 
+func (r *DeleteAllRequest[T]) In(db *DB) *DeleteAllRequest[T] {
+	r.db = db
+	return r
+}
+
+func (r *DeleteAllRequest[T]) Stats(s *Stats) *DeleteAllRequest[T] {
+	r.stats = s
+	return r
+}
+
 func (r *DeleteAllRequest[T]) Exec() error {
+	return r.ExecContext(context.Background())
+}
+
+func (r *DeleteAllRequest[T]) ExecContext(ctx context.Context) error {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb delete all: %w", err)
 	}
-	values, err := Query(r.value).BeginsWith(r.beginsWith).Between(r.betweenStart, r.betweenEnd).Exec()
+	db, err := resolve(r.db)
+	if err != nil {
+		return wrap(err)
+	}
+	ctx, done := db.track(ctx, OperationDeleteAll, r.stats)
+	defer done()
+	query := Query(r.value).In(db).BeginsWith(r.beginsWith).Between(r.betweenStart, r.betweenEnd)
+	query.operation = OperationDeleteAll
+	values, err := query.ExecContext(ctx)
 	if err != nil {
 		return wrap(err)
 	}
 	for _, value := range values {
-		if err := Delete(value).Exec(); err != nil {
+		if err := ctx.Err(); err != nil {
+			return wrap(err)
+		}
+		del := Delete(value).In(db)
+		del.operation = OperationDeleteAll
+		if err := del.ExecContext(ctx); err != nil {
 			return wrap(err)
 		}
 	}
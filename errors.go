@@ -0,0 +1,99 @@
+package goddb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// Errors returned by goddb can be matched with errors.Is against these
+// sentinels. Errors from DynamoDB keep the original SDK error in the chain,
+// so errors.As works with the types of the SDK as well.
+var (
+	// ErrItemNotFound is returned when an item to read does not exist.
+	ErrItemNotFound = errors.New("item not found")
+	// ErrConditionFailed is returned when the condition of a write, or of
+	// an item of a transaction, is not met.
+	ErrConditionFailed = errors.New("condition failed")
+	// ErrThrottled is returned when DynamoDB rejects a request because of
+	// throughput or request limits, after any retries.
+	ErrThrottled = errors.New("throttled")
+	// ErrValidation is returned for invalid requests, such as values with
+	// bad struct tags or zero key fields, whether found by goddb or by
+	// DynamoDB.
+	ErrValidation = errors.New("invalid request")
+	// ErrItemTooLarge is returned when an item exceeds the item size limit
+	// of DynamoDB.
+	ErrItemTooLarge = errors.New("item too large")
+	// ErrTransactionCanceled is returned when DynamoDB cancels a
+	// transaction.
+	ErrTransactionCanceled = errors.New("transaction canceled")
+	// ErrIdempotentParameterMismatch is returned when a transaction reuses
+	// the idempotency token of a different transaction.
+	ErrIdempotentParameterMismatch = errors.New("idempotent parameter mismatch")
+)
+
+// invalid marks err as a problem with a request found before it was sent.
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", ErrValidation, err)
+}
+
+var throttledCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"ThrottlingException":                    true,
+	"RequestLimitExceeded":                   true,
+	"ThrottlingError":                        true,
+	"ProvisionedThroughputExceeded":          true,
+}
+
+// classify prefixes an error returned by the client with the sentinels it
+// matches.
+func classify(err error) error {
+	var sentinels []error
+	var canceled *types.TransactionCanceledException
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &canceled):
+		sentinels = append(sentinels, ErrTransactionCanceled)
+		for _, reason := range canceled.CancellationReasons {
+			sentinels = appendSentinel(sentinels, aws.ToString(reason.Code), aws.ToString(reason.Message))
+		}
+	case errors.As(err, &apiErr):
+		sentinels = appendSentinel(sentinels, apiErr.ErrorCode(), apiErr.ErrorMessage())
+	}
+	if len(sentinels) == 0 {
+		return err
+	}
+	var args []any
+	for _, sentinel := range sentinels {
+		args = append(args, sentinel)
+	}
+	return fmt.Errorf(strings.Repeat("%w: ", len(sentinels))+"%w", append(args, err)...)
+}
+
+func appendSentinel(sentinels []error, code string, message string) []error {
+	var sentinel error
+	switch {
+	case code == "ConditionalCheckFailedException" || code == "ConditionalCheckFailed":
+		sentinel = ErrConditionFailed
+	case code == "IdempotentParameterMismatchException":
+		sentinel = ErrIdempotentParameterMismatch
+	case throttledCodes[code]:
+		sentinel = ErrThrottled
+	case (code == "ValidationException" || code == "ValidationError") && strings.Contains(strings.ToLower(message), "exceeded the maximum allowed size"):
+		sentinel = ErrItemTooLarge
+	case code == "ValidationException" || code == "ValidationError":
+		sentinel = ErrValidation
+	default:
+		return sentinels
+	}
+	if slices.Contains(sentinels, sentinel) {
+		return sentinels
+	}
+	return append(sentinels, sentinel)
+}
@@ -0,0 +1,64 @@
+package goddb
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Explanation describes the call a request would make to DynamoDB.
+type Explanation struct {
+	Operation Operation
+	// Input is the input of the call, such as *dynamodb.QueryInput. For
+	// requests that fetch several pages, it is the input of the first page.
+	Input any
+}
+
+// String renders the operation and the input of the call with one attribute
+// per line.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	b.WriteString(string(e.Operation))
+	b.WriteByte('\n')
+	explainFields(&b, e.Input, describe(e.Input, false)[1:], "  ")
+	return b.String()
+}
+
+// explain renders the call of input followed by its fields.
+func explain(b *strings.Builder, input any, indent string) {
+	attrs := describe(input, false)
+	b.WriteString(indent)
+	b.WriteString(attrs[0].Value.String())
+	b.WriteByte('\n')
+	explainFields(b, input, attrs[1:], indent+"  ")
+}
+
+func explainFields(b *strings.Builder, input any, attrs []slog.Attr, indent string) {
+	for _, attr := range attrs {
+		b.WriteString(indent)
+		b.WriteString(attr.Key)
+		b.WriteString(": ")
+		b.WriteString(attr.Value.String())
+		b.WriteByte('\n')
+	}
+	if input, ok := input.(*dynamodb.TransactGetItemsInput); ok {
+		for _, item := range input.TransactItems {
+			explain(b, item.Get, indent)
+		}
+	}
+	if input, ok := input.(*dynamodb.TransactWriteItemsInput); ok {
+		for _, item := range input.TransactItems {
+			switch {
+			case item.Put != nil:
+				explain(b, item.Put, indent)
+			case item.Update != nil:
+				explain(b, item.Update, indent)
+			case item.Delete != nil:
+				explain(b, item.Delete, indent)
+			case item.ConditionCheck != nil:
+				explain(b, item.ConditionCheck, indent)
+			}
+		}
+	}
+}
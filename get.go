@@ -2,56 +2,118 @@ package goddb
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 )
 
-var ErrItemNotFound = errors.New("item not found")
-
 type GetRequest[T any] struct {
-	value *T
-	input *dynamodb.GetItemInput
+	db        *DB
+	stats     *Stats
+	value     *T
+	input     *dynamodb.GetItemInput
+	selectors []func(*T) any
 }
 
 func (r *GetRequest[T]) Exec() (*T, error) {
+	return r.ExecContext(context.Background())
+}
+
+func (r *GetRequest[T]) ExecContext(ctx context.Context) (*T, error) {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb get: %w", err)
 	}
-	val, err := valueOf(r.value)
+	db, err := resolve(r.db)
 	if err != nil {
 		return r.value, wrap(err)
 	}
-	r.input.Key, err = makeItem(val.Type(), val, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	ctx, done := db.track(ctx, OperationGet, r.stats)
+	defer done()
+	input, err := r.build(db)
 	if err != nil {
-		return r.value, wrap(err)
+		return r.value, wrap(invalid(err))
 	}
-	output, err := client.GetItem(context.Background(), r.input)
+	output, err := send[dynamodb.GetItemOutput](ctx, db, OperationGet, r.value, input)
 	if err != nil {
 		return r.value, wrap(err)
 	}
 	if len(output.Item) == 0 {
-		return r.value, ErrItemNotFound
+		return r.value, wrap(ErrItemNotFound)
+	}
+	val, err := valueOf(r.value)
+	if err != nil {
+		return r.value, wrap(err)
 	}
-	if err := setFieldValues(val, output.Item); err != nil {
+	if err := setFieldValues(val, db.fromTable(output.Item), db.tagChar); err != nil {
 		return nil, wrap(err)
 	}
 	return r.value, nil
 }
 
+func (r *GetRequest[T]) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb get: %w", err)
+	}
+	db, err := resolve(r.db)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	input, err := r.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: OperationGet, Input: input}, nil
+}
+
+func (r *GetRequest[T]) build(db *DB) (*dynamodb.GetItemInput, error) {
+	val, err := valueOf(r.value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	if err != nil {
+		return nil, err
+	}
+	input := *r.input
+	input.TableName = aws.String(db.tableName)
+	input.Key = db.toTable(key)
+	if len(r.selectors) > 0 {
+		exp, names, err := projection(db, r.selectors)
+		if err != nil {
+			return nil, err
+		}
+		input.ProjectionExpression = &exp
+		input.ExpressionAttributeNames = names
+	}
+	return &input, nil
+}
+
 func (r *GetRequest[T]) Consistent() *GetRequest[T] {
 	r.input.ConsistentRead = aws.Bool(true)
 	return r
 }
 
+// Select fetches only the fields returned by the selectors and the fields
+// stored in the key. Other fields are left at their zero value.
+func (r *GetRequest[T]) Select(selectors ...func(*T) any) *GetRequest[T] {
+	r.selectors = append(r.selectors, selectors...)
+	return r
+}
+
+func (r *GetRequest[T]) In(db *DB) *GetRequest[T] {
+	r.db = db
+	return r
+}
+
+func (r *GetRequest[T]) Stats(s *Stats) *GetRequest[T] {
+	r.stats = s
+	return r
+}
+
 func Get[T any](v *T) *GetRequest[T] {
 	return &GetRequest[T]{
 		value: v,
-		input: &dynamodb.GetItemInput{
-			TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
-		},
+		input: &dynamodb.GetItemInput{},
 	}
 }
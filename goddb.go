@@ -2,18 +2,182 @@ package goddb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-var cfg = must(config.LoadDefaultConfig(context.Background()))
-var client = dynamodb.NewFromConfig(cfg)
-var TagChar = '#'
+// Client is the subset of the DynamoDB API used by goddb. It is satisfied by
+// *dynamodb.Client and can be replaced by fakes or instrumented wrappers.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+var _ Client = (*dynamodb.Client)(nil)
+
+// DB is a handle to a single DynamoDB table. Requests are executed against
+// the default DB unless another one is given with In.
+type DB struct {
+	client     Client
+	tableName  string
+	tagChar    rune
+	pkName     string
+	skName     string
+	middleware []Middleware
+	stats      func(ctx context.Context, stats *Stats)
+	retry      RetryPolicy
+	logger     *slog.Logger
+	redact     bool
+	handle     Handler
+}
+
+type Option func(*DB)
+
+// WithClient sets the client requests are sent to. If it is not given, a
+// *dynamodb.Client is created from the default AWS config.
+func WithClient(client Client) Option {
+	return func(db *DB) {
+		db.client = client
+	}
+}
+
+func WithTableName(name string) Option {
+	return func(db *DB) {
+		db.tableName = name
+	}
+}
 
-func must[T any](v T, err error) T {
-	if err != nil {
-		panic(err)
+// WithTagChar sets the character used to join tags and values in key
+// attributes. Defaults to '#'.
+func WithTagChar(c rune) Option {
+	return func(db *DB) {
+		db.tagChar = c
+	}
+}
+
+// WithKeyAttributes sets the names of the table's partition and sort key
+// attributes. Defaults to PK and SK.
+func WithKeyAttributes(pk string, sk string) Option {
+	return func(db *DB) {
+		db.pkName = pk
+		db.skName = sk
+	}
+}
+
+func New(opts ...Option) (*DB, error) {
+	db := &DB{
+		tagChar: '#',
+		pkName:  "PK",
+		skName:  "SK",
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	if db.tableName == "" {
+		return nil, errors.New("goddb: table name is required")
+	}
+	if db.pkName == "" || db.skName == "" {
+		return nil, errors.New("goddb: key attribute names are required")
+	}
+	if db.client == nil {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("goddb: %w", err)
+		}
+		db.client = dynamodb.NewFromConfig(cfg)
+	}
+	if db.logger != nil {
+		db.middleware = append(db.middleware, db.logging)
+	}
+	db.handle = db.handler()
+	return db, nil
+}
+
+var defaultDB struct {
+	sync.Mutex
+	db *DB
+}
+
+// Default returns the DB used by requests without In. Unless set with
+// SetDefault, it is created on first use from the default AWS config and
+// the GODDB_TABLE_NAME environment variable.
+func Default() (*DB, error) {
+	defaultDB.Lock()
+	defer defaultDB.Unlock()
+	if defaultDB.db == nil {
+		db, err := New(WithTableName(os.Getenv("GODDB_TABLE_NAME")))
+		if err != nil {
+			return nil, err
+		}
+		defaultDB.db = db
+	}
+	return defaultDB.db, nil
+}
+
+func SetDefault(db *DB) {
+	defaultDB.Lock()
+	defer defaultDB.Unlock()
+	defaultDB.db = db
+}
+
+func resolve(db *DB) (*DB, error) {
+	if db != nil {
+		return db, nil
+	}
+	return Default()
+}
+
+// attributeName maps the key attribute names used in goddb tags to the
+// attribute names of the table.
+func (db *DB) attributeName(name string) string {
+	switch name {
+	case "PK":
+		return db.pkName
+	case "SK":
+		return db.skName
+	}
+	return name
+}
+
+func (db *DB) toTable(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if db.pkName == "PK" && db.skName == "SK" {
+		return item
+	}
+	out := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		out[db.attributeName(k)] = v
+	}
+	return out
+}
+
+func (db *DB) fromTable(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if db.pkName == "PK" && db.skName == "SK" {
+		return item
+	}
+	out := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		switch k {
+		case db.pkName:
+			k = "PK"
+		case db.skName:
+			k = "SK"
+		}
+		out[k] = v
 	}
-	return v
+	return out
 }
@@ -1,13 +1,49 @@
 package goddb_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/twharmon/goddb"
+	"github.com/twharmon/goddb/goddbtest"
 )
 
+// client is nil when running against the table named by GODDB_TABLE_NAME.
+var client goddb.Client
+
+var tableName = os.Getenv("GODDB_TABLE_NAME")
+
+func TestMain(m *testing.M) {
+	if tableName == "" {
+		tableName = "goddb"
+		client = goddbtest.New()
+	}
+	db, err := goddb.New(goddb.WithTableName(tableName), goddb.WithClient(client))
+	if err != nil {
+		panic(err)
+	}
+	goddb.SetDefault(db)
+	os.Exit(m.Run())
+}
+
+func newDB(t *testing.T, opts ...goddb.Option) *goddb.DB {
+	db, err := goddb.New(append([]goddb.Option{goddb.WithTableName(tableName), goddb.WithClient(client)}, opts...)...)
+	assert.Equal(t, err, nil)
+	return db
+}
+
 func TestBasic(t *testing.T) {
 	type User struct {
 		ID   string `goddb:"PK,SK,UserGSI"`
@@ -151,11 +187,11 @@ func TestBasic(t *testing.T) {
 		assert.Equal(t, goddb.Delete(&Post{Author: "abc", ID: "abc"}).Exec(), nil)
 		assert.Equal(t, goddb.Delete(&Post{Author: "abc", ID: "def"}).Exec(), nil)
 		_, err := goddb.Get(&User{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 		_, err = goddb.Get(&Post{Author: "abc", ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 		_, err = goddb.Get(&Post{Author: "abc", ID: "def"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 }
 
@@ -170,9 +206,9 @@ func TestDeleteAll(t *testing.T) {
 		assert.Equal(t, goddb.Put(&User{ID: "def", Name: "Jane Doe"}).Exec(), nil)
 		assert.Equal(t, goddb.DeleteAll(&User{}).Exec(), nil)
 		_, err := goddb.Get(&User{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 		_, err = goddb.Get(&User{ID: "def"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 
 	type Post struct {
@@ -185,33 +221,73 @@ func TestDeleteAll(t *testing.T) {
 		assert.Equal(t, goddb.Put(&Post{ID: "ghi", Author: "def"}).Exec(), nil)
 		assert.Equal(t, goddb.DeleteAll(&Post{Author: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Post{ID: "abc", Author: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 		_, err = goddb.Get(&Post{ID: "def", Author: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 		_, err = goddb.Get(&Post{ID: "ghi", Author: "def"}).Consistent().Exec()
 		assert.Equal(t, err, nil)
 		assert.Equal(t, goddb.DeleteAll(&Post{Author: "def"}).Exec(), nil)
 		_, err = goddb.Get(&Post{ID: "ghi", Author: "def"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 
 }
 
+func TestExecContext(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK,UserGSI"`
+		Name string
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).ExecContext(ctx), context.Canceled)
+	_, err := goddb.Get(&User{ID: "abc"}).ExecContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = goddb.Query(&User{}).ExecContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, goddb.DeleteAll(&User{}).ExecContext(ctx), context.Canceled)
+}
+
+type getItemClient struct {
+	goddb.Client
+	item map[string]types.AttributeValue
+}
+
+func (c *getItemClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: c.item}, nil
+}
+
+func TestClient(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	client := &getItemClient{item: map[string]types.AttributeValue{
+		"PK":   &types.AttributeValueMemberS{Value: "User#abc"},
+		"SK":   &types.AttributeValueMemberS{Value: "User#abc"},
+		"Name": &types.AttributeValueMemberS{Value: "Jon Doe"},
+	}}
+	db, err := goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(client))
+	assert.Equal(t, err, nil)
+	user, err := goddb.Get(&User{ID: "abc"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.Name, "Jon Doe")
+}
+
 func TestCustomTagChar(t *testing.T) {
-	goddb.TagChar = ':'
+	db := newDB(t, goddb.WithTagChar(':'))
 	type User struct {
 		ID   string `goddb:"PK,SK,UserGSI"`
 		Name string
 	}
-	assert.Equal(t, goddb.Put(&User{ID: "abc#def", Name: "Jon Doe"}).Exec(), nil)
-	user, err := goddb.Get(&User{ID: "abc#def"}).Consistent().Exec()
+	assert.Equal(t, goddb.Put(&User{ID: "abc#def", Name: "Jon Doe"}).In(db).Exec(), nil)
+	user, err := goddb.Get(&User{ID: "abc#def"}).In(db).Consistent().Exec()
 	assert.Equal(t, err, nil)
 	assert.Equal(t, user.ID, "abc#def")
 	assert.Equal(t, user.Name, "Jon Doe")
-	assert.Equal(t, goddb.Delete(&User{ID: "abc#def"}).Exec(), nil)
-	_, err = goddb.Get(&User{ID: "abc#def"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
-	goddb.TagChar = '#'
+	assert.Equal(t, goddb.Delete(&User{ID: "abc#def"}).In(db).Exec(), nil)
+	_, err = goddb.Get(&User{ID: "abc#def"}).In(db).Consistent().Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	assert.ErrorContains(t, goddb.Put(&User{ID: "abc#def", Name: "Jon Doe"}).Exec(), "tag char")
 }
 
@@ -231,10 +307,10 @@ func TestComplexPKZeroValueQuery(t *testing.T) {
 
 	assert.Equal(t, goddb.Delete(&User{ID: 1, Org: 1, Name: "Jon Doe"}).Exec(), nil)
 	_, err = goddb.Get(&User{ID: 1, Org: 1, Name: "Jon Doe"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	assert.Equal(t, goddb.Delete(&User{ID: 2, Org: 1, Name: "Jane Doe"}).Exec(), nil)
 	_, err = goddb.Get(&User{ID: 2, Org: 1, Name: "Jane Doe"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestNumbers(t *testing.T) {
@@ -248,7 +324,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputInt.Num, int(-2))
 	assert.Equal(t, goddb.Delete(&Int{ID: -1}).Exec(), nil)
 	_, err = goddb.Get(&Int{ID: -1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Uint struct {
 		ID  uint `goddb:"PK,SK"`
@@ -260,7 +336,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputUint.Num, uint(2))
 	assert.Equal(t, goddb.Delete(&Uint{ID: 1}).Exec(), nil)
 	_, err = goddb.Get(&Uint{ID: 1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Int8 struct {
 		ID  int8 `goddb:"PK,SK"`
@@ -272,7 +348,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputInt8.Num, int8(-2))
 	assert.Equal(t, goddb.Delete(&Int8{ID: -1}).Exec(), nil)
 	_, err = goddb.Get(&Int8{ID: -1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Uint8 struct {
 		ID  uint8 `goddb:"PK,SK"`
@@ -284,7 +360,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputUint8.Num, uint8(2))
 	assert.Equal(t, goddb.Delete(&Uint8{ID: 1}).Exec(), nil)
 	_, err = goddb.Get(&Uint8{ID: 1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Int16 struct {
 		ID  int16 `goddb:"PK,SK"`
@@ -296,7 +372,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputInt16.Num, int16(-2))
 	assert.Equal(t, goddb.Delete(&Int16{ID: -1}).Exec(), nil)
 	_, err = goddb.Get(&Int16{ID: -1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Uint16 struct {
 		ID  uint16 `goddb:"PK,SK"`
@@ -308,7 +384,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputUint16.Num, uint16(2))
 	assert.Equal(t, goddb.Delete(&Uint16{ID: 1}).Exec(), nil)
 	_, err = goddb.Get(&Uint16{ID: 1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Int32 struct {
 		ID  int32 `goddb:"PK,SK"`
@@ -320,7 +396,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputInt32.Num, int32(-2))
 	assert.Equal(t, goddb.Delete(&Int32{ID: -1}).Exec(), nil)
 	_, err = goddb.Get(&Int32{ID: -1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Uint32 struct {
 		ID  uint32 `goddb:"PK,SK"`
@@ -332,7 +408,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputUint32.Num, uint32(2))
 	assert.Equal(t, goddb.Delete(&Uint32{ID: 1}).Exec(), nil)
 	_, err = goddb.Get(&Uint32{ID: 1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Int64 struct {
 		ID  int64 `goddb:"PK,SK"`
@@ -344,7 +420,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputInt64.Num, int64(-2))
 	assert.Equal(t, goddb.Delete(&Int64{ID: -1}).Exec(), nil)
 	_, err = goddb.Get(&Int64{ID: -1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Uint64 struct {
 		ID  uint64 `goddb:"PK,SK"`
@@ -356,7 +432,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputUint64.Num, uint64(2))
 	assert.Equal(t, goddb.Delete(&Uint64{ID: 1}).Exec(), nil)
 	_, err = goddb.Get(&Uint64{ID: 1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Float32 struct {
 		ID  float32 `goddb:"PK,SK"`
@@ -368,7 +444,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputFloat32.Num, float32(-2.2))
 	assert.Equal(t, goddb.Delete(&Float32{ID: -1.1}).Exec(), nil)
 	_, err = goddb.Get(&Float32{ID: -1.1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type Float64 struct {
 		ID  float64 `goddb:"PK,SK"`
@@ -380,7 +456,7 @@ func TestNumbers(t *testing.T) {
 	assert.Equal(t, outputFloat64.Num, float64(-2.2))
 	assert.Equal(t, goddb.Delete(&Float64{ID: -1.1}).Exec(), nil)
 	_, err = goddb.Get(&Float64{ID: -1.1}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 // test time.Duration
@@ -395,7 +471,7 @@ func TestDuration(t *testing.T) {
 	assert.Equal(t, outputDuration.Num, 10*time.Second)
 	assert.Equal(t, goddb.Delete(&Duration{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&Duration{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestSets(t *testing.T) {
@@ -409,7 +485,7 @@ func TestSets(t *testing.T) {
 	assert.Equal(t, len(outputStringSet.Values), 3)
 	assert.Equal(t, goddb.Delete(&StringSet{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&StringSet{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type IntSet struct {
 		ID     string `goddb:"PK,SK"`
@@ -421,7 +497,7 @@ func TestSets(t *testing.T) {
 	assert.Equal(t, len(outputIntSet.Values), 3)
 	assert.Equal(t, goddb.Delete(&IntSet{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&IntSet{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type FloatSet struct {
 		ID     string `goddb:"PK,SK"`
@@ -433,7 +509,7 @@ func TestSets(t *testing.T) {
 	assert.Equal(t, len(outputFloatSet.Values), 3)
 	assert.Equal(t, goddb.Delete(&FloatSet{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&FloatSet{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 
 	type TimeSet struct {
 		ID     string `goddb:"PK,SK"`
@@ -448,7 +524,7 @@ func TestSets(t *testing.T) {
 	assert.Equal(t, len(outputTimeSet.Values), 3)
 	assert.Equal(t, goddb.Delete(&TimeSet{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&TimeSet{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestBool(t *testing.T) {
@@ -466,7 +542,7 @@ func TestBool(t *testing.T) {
 	assert.Equal(t, output.Value, false)
 	assert.Equal(t, goddb.Delete(&Bool{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&Bool{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestTime(t *testing.T) {
@@ -486,7 +562,7 @@ func TestTime(t *testing.T) {
 	assert.Equal(t, output.Value.UTC().Format(time.RFC3339Nano), time.Time{}.UTC().Format(time.RFC3339Nano))
 	assert.Equal(t, goddb.Delete(&Time{ID: id}).Exec(), nil)
 	_, err = goddb.Get(&Time{ID: id}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestUpdateSet(t *testing.T) {
@@ -501,7 +577,7 @@ func TestUpdateSet(t *testing.T) {
 	assert.Equal(t, update.Foo, "bar")
 	assert.Equal(t, goddb.Delete(&Update{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&Update{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestUpdateAdd(t *testing.T) {
@@ -516,7 +592,7 @@ func TestUpdateAdd(t *testing.T) {
 	assert.Equal(t, update.Foo, 3)
 	assert.Equal(t, goddb.Delete(&Update{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&Update{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestUpdateRemove(t *testing.T) {
@@ -537,7 +613,7 @@ func TestUpdateRemove(t *testing.T) {
 	assert.Equal(t, update.Foo, 0)
 	assert.Equal(t, goddb.Delete(&Update{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&Update{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestUpdateDelete(t *testing.T) {
@@ -555,7 +631,7 @@ func TestUpdateDelete(t *testing.T) {
 	assert.Equal(t, update.Set[0], "foo")
 	assert.Equal(t, goddb.Delete(&Update{ID: "abc"}).Exec(), nil)
 	_, err = goddb.Get(&Update{ID: "abc"}).Consistent().Exec()
-	assert.Equal(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 }
 
 func TestConditionals(t *testing.T) {
@@ -567,10 +643,10 @@ func TestConditionals(t *testing.T) {
 		}
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo", Bar: "bar"}).Exec(), nil)
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar", Bar: "bar"}).If(goddb.Equal(&Put{Foo: "foo", Bar: "bar"})).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "baz", Bar: "bar"}).If(goddb.Equal(&Put{Foo: "foo", Bar: "bar"})).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "baz", Bar: "bar"}).If(goddb.Equal(&Put{Foo: "foo", Bar: "bar"})).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("not equal", func(t *testing.T) {
 		type Put struct {
@@ -579,10 +655,10 @@ func TestConditionals(t *testing.T) {
 		}
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo"}).Exec(), nil)
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.NotEqual(&Put{Foo: "bar"})).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "baz"}).If(goddb.NotEqual(&Put{Foo: "bar"})).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "baz"}).If(goddb.NotEqual(&Put{Foo: "bar"})).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("less than", func(t *testing.T) {
 		type Put struct {
@@ -591,10 +667,10 @@ func TestConditionals(t *testing.T) {
 		}
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo"}).Exec(), nil)
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.LessThan(&Put{Foo: "food"})).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "baz"}).If(goddb.LessThan(&Put{Foo: "bar"})).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "baz"}).If(goddb.LessThan(&Put{Foo: "bar"})).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("and", func(t *testing.T) {
 		type Put struct {
@@ -605,10 +681,10 @@ func TestConditionals(t *testing.T) {
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo", Bar: "bar"}).Exec(), nil)
 		cond := goddb.And(goddb.Equal(&Put{Foo: "foo"}), goddb.NotEqual(&Put{Bar: "baz"}))
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar", Bar: "bar"}).If(cond).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "baz", Bar: "bar"}).If(cond).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "baz", Bar: "bar"}).If(cond).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("or", func(t *testing.T) {
 		type Put struct {
@@ -619,10 +695,10 @@ func TestConditionals(t *testing.T) {
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo", Bar: "bar"}).Exec(), nil)
 		cond := goddb.Or(goddb.Equal(&Put{Foo: "foo"}), goddb.NotEqual(&Put{Bar: "bar"}))
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar", Bar: "bar"}).If(cond).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "baz", Bar: "bar"}).If(cond).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "baz", Bar: "bar"}).If(cond).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("complex", func(t *testing.T) {
 		type Update struct {
@@ -642,10 +718,10 @@ func TestConditionals(t *testing.T) {
 			),
 		)
 		assert.Equal(t, goddb.Put(&Update{ID: "abc", Foo: "bar", Bar: "bar"}).If(cond).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Update{ID: "abc", Foo: "baz", Bar: "bar"}).If(cond).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Update{ID: "abc", Foo: "baz", Bar: "bar"}).If(cond).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Update{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Update{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("attribute exists", func(t *testing.T) {
 		type Put struct {
@@ -655,10 +731,10 @@ func TestConditionals(t *testing.T) {
 		}
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo", Bar: "bar"}).Exec(), nil)
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.AttributeExists(func(p *Put) any { return p.Bar })).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.AttributeExists(func(p *Put) any { return p.Bar })).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.AttributeExists(func(p *Put) any { return p.Bar })).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("attribute not exists", func(t *testing.T) {
 		type Put struct {
@@ -668,10 +744,10 @@ func TestConditionals(t *testing.T) {
 		}
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo"}).Exec(), nil)
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo", Bar: "bar"}).If(goddb.AttributeNotExists(func(p *Put) any { return p.Bar })).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.AttributeNotExists(func(p *Put) any { return p.Bar })).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc", Foo: "bar"}).If(goddb.AttributeNotExists(func(p *Put) any { return p.Bar })).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
 	})
 	t.Run("attribute not exists: primary key", func(t *testing.T) {
 		type Put struct {
@@ -680,9 +756,1085 @@ func TestConditionals(t *testing.T) {
 			Bar string
 		}
 		assert.Equal(t, goddb.Put(&Put{ID: "abc", Foo: "foo"}).Exec(), nil)
-		assert.Equal(t, goddb.Put(&Put{ID: "abc"}).If(goddb.AttributeNotExists(func(p *Put) any { return p.ID })).Exec(), goddb.ErrConditionFailed)
+		assert.ErrorIs(t, goddb.Put(&Put{ID: "abc"}).If(goddb.AttributeNotExists(func(p *Put) any { return p.ID })).Exec(), goddb.ErrConditionFailed)
 		assert.Equal(t, goddb.Delete(&Put{ID: "abc"}).Exec(), nil)
 		_, err := goddb.Get(&Put{ID: "abc"}).Consistent().Exec()
-		assert.Equal(t, err, goddb.ErrItemNotFound)
+		assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+	})
+}
+
+func TestQueryGSIPagination(t *testing.T) {
+	type Post struct {
+		ID       string `goddb:"SK,GSI1SK"`
+		Author   string `goddb:"PK"`
+		Category string `goddb:"GSI1PK"`
+	}
+	assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: "abc", Category: "foo"}).Exec(), nil)
+	assert.Equal(t, goddb.Put(&Post{Author: "def", ID: "def", Category: "foo"}).Exec(), nil)
+	var offset string
+	posts, err := goddb.Query(&Post{Category: "foo"}).Page(1, &offset).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(posts), 1)
+	assert.Equal(t, posts[0].ID, "abc")
+	posts, err = goddb.Query(&Post{Category: "foo"}).Page(1, &offset).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(posts), 1)
+	assert.Equal(t, posts[0].ID, "def")
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "abc"}).Exec(), nil)
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "def"}).Exec(), nil)
+}
+
+func TestKeyAttributes(t *testing.T) {
+	store := goddbtest.New(goddbtest.WithKeyAttributes("pk", "sk"))
+	db, err := goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(store), goddb.WithKeyAttributes("pk", "sk"))
+	assert.Equal(t, err, nil)
+	type Post struct {
+		ID     string `goddb:"SK"`
+		Author string `goddb:"PK"`
+		Body   string
+	}
+	assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: "abc", Body: "Foo bar"}).In(db).Exec(), nil)
+	items := store.Items("goddb")
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0]["pk"], &types.AttributeValueMemberS{Value: "Author#abc"})
+	assert.Equal(t, items[0]["sk"], &types.AttributeValueMemberS{Value: "Post#abc"})
+	post, err := goddb.Get(&Post{Author: "abc", ID: "abc"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Body, "Foo bar")
+	posts, err := goddb.Query(&Post{Author: "abc"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(posts), 1)
+	assert.Equal(t, posts[0].ID, "abc")
+	assert.Equal(t, goddb.Delete(&Post{Author: "abc", ID: "abc"}).In(db).Exec(), nil)
+	assert.Equal(t, len(store.Items("goddb")), 0)
+}
+
+func TestMiddleware(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	var ops []goddb.Operation
+	record := func(next goddb.Handler) goddb.Handler {
+		return func(ctx context.Context, req *goddb.Request) (any, error) {
+			ops = append(ops, req.Operation)
+			return next(ctx, req)
+		}
+	}
+	cache := func(next goddb.Handler) goddb.Handler {
+		return func(ctx context.Context, req *goddb.Request) (any, error) {
+			if input, ok := req.Input.(*dynamodb.GetItemInput); ok && input.Key["PK"].(*types.AttributeValueMemberS).Value == "User#cached" {
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"PK":   &types.AttributeValueMemberS{Value: "User#cached"},
+					"SK":   &types.AttributeValueMemberS{Value: "User#cached"},
+					"Name": &types.AttributeValueMemberS{Value: "Jane Doe"},
+				}}, nil
+			}
+			return next(ctx, req)
+		}
+	}
+	db := newDB(t, goddb.WithMiddleware(record, cache))
+	assert.Equal(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).In(db).Exec(), nil)
+	user, err := goddb.Get(&User{ID: "cached"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.Name, "Jane Doe")
+	assert.Equal(t, goddb.Update(&User{ID: "abc"}).In(db).Set(&User{Name: "Jane Doe"}).Exec(), nil)
+	_, err = goddb.Query(&User{ID: "abc"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, goddb.DeleteAll(&User{ID: "abc"}).In(db).Exec(), nil)
+	assert.Equal(t, ops, []goddb.Operation{
+		goddb.OperationPut,
+		goddb.OperationGet,
+		goddb.OperationUpdate,
+		goddb.OperationQuery,
+		goddb.OperationDeleteAll,
+		goddb.OperationDeleteAll,
+	})
+}
+
+func TestStats(t *testing.T) {
+	type Post struct {
+		ID       string `goddb:"SK,GSI1SK"`
+		Author   string `goddb:"PK"`
+		Category string `goddb:"GSI1PK"`
+	}
+	var sunk []goddb.Operation
+	db := newDB(t, goddb.WithStats(func(ctx context.Context, stats *goddb.Stats) {
+		sunk = append(sunk, stats.Operation)
+	}))
+	var stats goddb.Stats
+	assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: "abc", Category: "foo"}).In(db).Stats(&stats).Exec(), nil)
+	assert.Equal(t, stats.Operation, goddb.OperationPut)
+	assert.Equal(t, stats.Pages, 1)
+	assert.Equal(t, len(stats.Capacity), 1)
+	assert.Equal(t, stats.Capacity[0].Table, tableName)
+	assert.Greater(t, stats.Capacity[0].Write, 0.0)
+	assert.Equal(t, goddb.Put(&Post{Author: "def", ID: "def", Category: "foo"}).In(db).Exec(), nil)
+	var offset string
+	_, err := goddb.Query(&Post{Category: "foo"}).In(db).Page(2, &offset).Stats(&stats).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stats.Operation, goddb.OperationQuery)
+	assert.Equal(t, stats.Count, 2)
+	assert.Equal(t, stats.ScannedCount, 2)
+	assert.Equal(t, len(stats.Capacity), 1)
+	assert.Equal(t, stats.Capacity[0].Index, "GSI1")
+	assert.Greater(t, stats.Capacity[0].Read, 0.0)
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "abc"}).In(db).Stats(&stats).Exec(), nil)
+	assert.Equal(t, stats.Operation, goddb.OperationDeleteAll)
+	assert.Equal(t, stats.Pages, 2)
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "def"}).In(db).Exec(), nil)
+	assert.Equal(t, sunk, []goddb.Operation{goddb.OperationPut, goddb.OperationPut, goddb.OperationQuery, goddb.OperationDeleteAll, goddb.OperationDeleteAll})
+}
+
+type flakyClient struct {
+	goddb.Client
+	failures int
+	calls    int
+	err      error
+}
+
+func (c *flakyClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, c.err
+	}
+	return c.Client.PutItem(ctx, params, optFns...)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	policy := goddb.WithRetryPolicy(goddb.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	throttled := &types.ProvisionedThroughputExceededException{Message: aws.String("throttled")}
+
+	flaky := &flakyClient{Client: goddbtest.New(), failures: 2, err: throttled}
+	db, err := goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(flaky), policy)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).In(db).Exec(), nil)
+	assert.Equal(t, flaky.calls, 3)
+
+	flaky = &flakyClient{Client: goddbtest.New(), failures: 3, err: throttled}
+	db, err = goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(flaky), policy)
+	assert.Equal(t, err, nil)
+	assert.ErrorAs(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).In(db).Exec(), &throttled)
+	assert.Equal(t, flaky.calls, 3)
+
+	flaky = &flakyClient{Client: goddbtest.New(), failures: 3, err: &types.ConditionalCheckFailedException{Message: aws.String("failed")}}
+	db, err = goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(flaky), policy)
+	assert.Equal(t, err, nil)
+	assert.ErrorIs(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).In(db).Exec(), goddb.ErrConditionFailed)
+	assert.Equal(t, flaky.calls, 1)
+}
+
+func TestLogger(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	db := newDB(t, goddb.WithLogger(logger))
+	assert.Equal(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).In(db).If(goddb.NotEqual(&User{Name: "secret"})).Exec(), nil)
+	assert.Contains(t, buf.String(), "op=Put")
+	assert.Contains(t, buf.String(), "call=PutItem")
+	assert.Contains(t, buf.String(), "condition=")
+	assert.Contains(t, buf.String(), "secret")
+
+	buf.Reset()
+	db = newDB(t, goddb.WithLogger(logger), goddb.WithRedaction())
+	assert.Equal(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).In(db).If(goddb.NotEqual(&User{Name: "secret"})).Exec(), nil)
+	assert.Contains(t, buf.String(), "op=Put")
+	assert.NotContains(t, buf.String(), "secret")
+	assert.NotContains(t, buf.String(), "abc")
+	assert.Equal(t, goddb.Delete(&User{ID: "abc"}).In(db).Exec(), nil)
+}
+
+func TestExplain(t *testing.T) {
+	type Post struct {
+		ID       string `goddb:"SK,GSI1SK"`
+		Author   string `goddb:"PK"`
+		Category string `goddb:"GSI1PK"`
+		Likes    int
+	}
+	db := newDB(t)
+	explanation, err := goddb.Query(&Post{Category: "foo"}).In(db).Page(10, new(string)).Explain()
+	assert.Equal(t, err, nil)
+	input := explanation.Input.(*dynamodb.QueryInput)
+	assert.Equal(t, *input.IndexName, "GSI1")
+	assert.Equal(t, *input.KeyConditionExpression, "#pk = :pk and begins_with(#sk, :sk)")
+	assert.Equal(t, explanation.String(), `Query
+  table: `+tableName+`
+  index: GSI1
+  key_condition: #pk = :pk and begins_with(#sk, :sk)
+  names: {#pk: GSI1PK, #sk: GSI1SK}
+  values: {:pk: "Category#foo", :sk: "Post#"}
+  limit: 10
+`)
+
+	update := goddb.Update(&Post{Author: "abc", ID: "abc"}).In(db).Add(&Post{Likes: 1}).If(goddb.Equal(&Post{Category: "foo"}))
+	first, err := update.Explain()
+	assert.Equal(t, err, nil)
+	second, err := update.Explain()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, first.Input, second.Input)
+	assert.Equal(t, *first.Input.(*dynamodb.UpdateItemInput).UpdateExpression, "ADD #Likes :0")
+	assert.Equal(t, len(first.Input.(*dynamodb.UpdateItemInput).ExpressionAttributeValues), 2)
+
+	explanation, err = goddb.TransactionWrite().In(db).Put(&Post{Author: "abc", ID: "abc", Category: "foo"}).Delete(&Post{Author: "abc", ID: "def", Category: "foo"}).Explain()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(explanation.Input.(*dynamodb.TransactWriteItemsInput).TransactItems), 2)
+	assert.Contains(t, explanation.String(), "TransactionWrite\n  items: 2\n  Put\n")
+	assert.Contains(t, explanation.String(), "\n  Delete\n    table: ")
+	_, err = goddb.Get(&Post{Author: "abc", ID: "abc"}).In(db).Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+}
+
+func TestBatchGet(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	type Post struct {
+		ID     string `goddb:"SK"`
+		Author string `goddb:"PK"`
+		Body   string
+	}
+	db := newDB(t)
+	if client != nil {
+		db = newDB(t, goddb.WithClient(goddbtest.New(goddbtest.WithBatchSize(30))))
+	}
+	var users []any
+	for i := range 150 {
+		id := strconv.Itoa(i)
+		if i%2 == 0 {
+			assert.Equal(t, goddb.Put(&User{ID: id, Name: "user " + id}).In(db).Exec(), nil)
+		}
+		users = append(users, &User{ID: id})
+	}
+	assert.Equal(t, goddb.Put(&Post{Author: "1", ID: "abc", Body: "Foo bar"}).In(db).Exec(), nil)
+	post := &Post{Author: "1", ID: "abc"}
+	var stats goddb.Stats
+	err := goddb.BatchGet(append(users, post)...).In(db).Stats(&stats).Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+	var notFound *goddb.NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.Equal(t, len(notFound.Values), 75)
+	assert.Equal(t, notFound.Values[0], &User{ID: "1"})
+	assert.Equal(t, users[0], &User{ID: "0", Name: "user 0"})
+	assert.Equal(t, users[148], &User{ID: "148", Name: "user 148"})
+	assert.Equal(t, post.Body, "Foo bar")
+	assert.GreaterOrEqual(t, stats.Pages, 2)
+	assert.Equal(t, goddb.BatchGet(&User{ID: "0"}, &User{ID: "0"}, post).In(db).Exec(), nil)
+	for i := 0; i < 150; i += 2 {
+		assert.Equal(t, goddb.Delete(&User{ID: strconv.Itoa(i)}).In(db).Exec(), nil)
+	}
+	assert.Equal(t, goddb.Delete(post).In(db).Exec(), nil)
+}
+
+type failingBatchWriteClient struct {
+	goddb.Client
+}
+
+func (c *failingBatchWriteClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	return nil, &types.ProvisionedThroughputExceededException{Message: aws.String("throttled")}
+}
+
+func TestBatchWrite(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	store := goddbtest.New(goddbtest.WithBatchSize(10))
+	db, err := goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(store))
+	assert.Equal(t, err, nil)
+	write := goddb.BatchWrite().In(db)
+	for i := range 60 {
+		id := strconv.Itoa(i)
+		write.Put(&User{ID: id, Name: "user " + id})
+	}
+	write.Put(&User{ID: "0", Name: "Jon Doe"})
+	assert.Equal(t, write.Exec(), nil)
+	assert.Equal(t, len(store.Items("goddb")), 60)
+	user, err := goddb.Get(&User{ID: "0"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.Name, "Jon Doe")
+
+	write = goddb.BatchWrite().In(db)
+	for i := range 60 {
+		write.Delete(&User{ID: strconv.Itoa(i)})
+	}
+	assert.Equal(t, write.Exec(), nil)
+	assert.Equal(t, len(store.Items("goddb")), 0)
+
+	assert.Equal(t, goddb.BatchWrite().In(db).Delete(&User{ID: "a"}).Put(&User{ID: "a", Name: "new"}).Exec(), nil)
+	user, err = goddb.Get(&User{ID: "a"}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.Name, "new")
+	assert.Equal(t, goddb.BatchWrite().In(db).Put(&User{ID: "a", Name: "new"}).Delete(&User{ID: "a"}).Exec(), nil)
+	_, err = goddb.Get(&User{ID: "a"}).In(db).Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+
+	assert.NotEqual(t, goddb.BatchWrite().In(db).Put(&User{}).Exec(), nil)
+
+	db, err = goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(&failingBatchWriteClient{Client: store}))
+	assert.Equal(t, err, nil)
+	err = goddb.BatchWrite().In(db).Put(&User{ID: "abc"}).Delete(&User{ID: "def"}).Exec()
+	var batchErr *goddb.BatchWriteError
+	assert.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, len(batchErr.Failures), 2)
+	assert.Equal(t, batchErr.Failures[0].Value, &User{ID: "abc"})
+	var throttled *types.ProvisionedThroughputExceededException
+	assert.ErrorAs(t, err, &throttled)
+}
+
+func TestTransactionGet(t *testing.T) {
+	type Order struct {
+		ID    string `goddb:"PK,SK"`
+		Total int
+	}
+	type Summary struct {
+		OrderID string `goddb:"PK"`
+		ID      string `goddb:"SK"`
+		Items   int
+	}
+	assert.Equal(t, goddb.TransactionWrite().Put(&Order{ID: "abc", Total: 30}).Put(&Summary{OrderID: "abc", ID: "abc", Items: 3}).Exec(), nil)
+	order, summary := &Order{ID: "abc"}, &Summary{OrderID: "abc", ID: "abc"}
+	assert.Equal(t, goddb.TransactionGet().Get(order).Get(summary).Exec(), nil)
+	assert.Equal(t, order.Total, 30)
+	assert.Equal(t, summary.Items, 3)
+	missing := &Order{ID: "def"}
+	err := goddb.TransactionGet().Get(order).Get(missing).Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+	var notFound *goddb.NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.Equal(t, notFound.Values, []any{missing})
+	assert.Equal(t, goddb.TransactionWrite().Delete(&Order{ID: "abc"}).Delete(&Summary{OrderID: "abc", ID: "abc"}).Exec(), nil)
+}
+
+func TestSelect(t *testing.T) {
+	type Post struct {
+		ID       string `goddb:"SK,GSI1SK"`
+		Author   string `goddb:"PK"`
+		Category string `goddb:"GSI1PK"`
+		Title    string
+		Body     string
+		Likes    int
+	}
+	assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: "abc", Category: "foo", Title: "Foo", Body: "Foo bar", Likes: 3}).Exec(), nil)
+	post, err := goddb.Get(&Post{Author: "abc", ID: "abc"}).Select(func(p *Post) any { return p.Title }, func(p *Post) any { return p.Category }).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post, &Post{Author: "abc", ID: "abc", Category: "foo", Title: "Foo"})
+	posts, err := goddb.Query(&Post{Author: "abc"}).Select(func(p *Post) any { return p.Likes }).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, posts, []*Post{{Author: "abc", ID: "abc", Likes: 3}})
+	posts, err = goddb.Query(&Post{Category: "foo"}).Select(func(p *Post) any { return p.Title }).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, posts, []*Post{{Author: "abc", ID: "abc", Category: "foo", Title: "Foo"}})
+	assert.Equal(t, goddb.Delete(&Post{Author: "abc", ID: "abc"}).Exec(), nil)
+}
+
+func TestReturnValues(t *testing.T) {
+	type Counter struct {
+		ID    string `goddb:"PK,SK"`
+		Name  string
+		Count int
+	}
+	var old Counter
+	assert.Equal(t, goddb.Put(&Counter{ID: "abc", Name: "visits", Count: 1}).ReturnOld(&old).Exec(), nil)
+	assert.Equal(t, old, Counter{})
+	assert.Equal(t, goddb.Put(&Counter{ID: "abc", Name: "visits", Count: 2}).ReturnOld(&old).Exec(), nil)
+	assert.Equal(t, old, Counter{ID: "abc", Name: "visits", Count: 1})
+
+	var updated Counter
+	assert.Equal(t, goddb.Update(&Counter{ID: "abc"}).Add(&Counter{Count: 3}).ReturnUpdatedNew(&updated).Exec(), nil)
+	assert.Equal(t, updated, Counter{Count: 5})
+	var counter Counter
+	assert.Equal(t, goddb.Update(&Counter{ID: "abc"}).Add(&Counter{Count: 1}).ReturnNew(&counter).Exec(), nil)
+	assert.Equal(t, counter, Counter{ID: "abc", Name: "visits", Count: 6})
+	assert.Equal(t, goddb.Update(&Counter{ID: "abc"}).Add(&Counter{Count: 1}).ReturnOld(&old).Exec(), nil)
+	assert.Equal(t, old.Count, 6)
+
+	var existing Counter
+	err := goddb.Put(&Counter{ID: "abc"}).If(goddb.Equal(&Counter{Count: 1})).ReturnOldOnConditionFailure(&existing).Exec()
+	assert.ErrorIs(t, err, goddb.ErrConditionFailed)
+	assert.Equal(t, existing, Counter{ID: "abc", Name: "visits", Count: 7})
+
+	var deleted Counter
+	assert.Equal(t, goddb.Delete(&Counter{ID: "abc"}).ReturnOld(&deleted).Exec(), nil)
+	assert.Equal(t, deleted, Counter{ID: "abc", Name: "visits", Count: 7})
+}
+
+type transactionErrorClient struct {
+	goddb.Client
+	err error
+}
+
+func (c *transactionErrorClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, c.err
+}
+
+func TestErrors(t *testing.T) {
+	type User struct {
+		ID   string `goddb:"PK,SK"`
+		Name string
+	}
+	assert.Equal(t, goddb.Put(&User{ID: "abc", Name: "Jon Doe"}).Exec(), nil)
+
+	err := goddb.Update(&User{ID: "abc"}).Set(&User{Name: "Jane Doe"}).If(goddb.Equal(&User{Name: "Jane Doe"})).Exec()
+	assert.ErrorIs(t, err, goddb.ErrConditionFailed)
+	var ccf *types.ConditionalCheckFailedException
+	assert.ErrorAs(t, err, &ccf)
+	assert.ErrorIs(t, goddb.Delete(&User{ID: "abc"}).If(goddb.Equal(&User{Name: "Jane Doe"})).Exec(), goddb.ErrConditionFailed)
+
+	_, err = goddb.Get(&User{ID: "def"}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+	assert.ErrorIs(t, goddb.BatchGet(&User{ID: "def"}).Exec(), goddb.ErrItemNotFound)
+
+	_, err = goddb.Get(&User{ID: "a#b"}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	assert.ErrorIs(t, goddb.Put(&User{Name: "Jon Doe"}).Exec(), goddb.ErrValidation)
+	assert.ErrorIs(t, goddb.BatchWrite().Put(&User{}).Exec(), goddb.ErrValidation)
+	assert.ErrorIs(t, goddb.Update(&User{ID: "a#b"}).Set(&User{Name: "Jane Doe"}).Exec(), goddb.ErrValidation)
+
+	err = goddb.Put(&User{ID: "abc", Name: strings.Repeat("a", 500*1024)}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemTooLarge)
+	assert.Equal(t, errors.Is(err, goddb.ErrValidation), false)
+
+	throttled := &flakyClient{Client: goddbtest.New(), failures: 1, err: &types.ProvisionedThroughputExceededException{Message: aws.String("throttled")}}
+	db := newDB(t, goddb.WithClient(throttled))
+	assert.ErrorIs(t, goddb.Put(&User{ID: "abc"}).In(db).Exec(), goddb.ErrThrottled)
+
+	canceled := &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("None")},
+		{Code: aws.String("ConditionalCheckFailed")},
+	}}
+	db = newDB(t, goddb.WithClient(&transactionErrorClient{Client: goddbtest.New(), err: canceled}))
+	err = goddb.TransactionWrite().In(db).Put(&User{ID: "abc"}).Put(&User{ID: "def"}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrTransactionCanceled)
+	assert.ErrorIs(t, err, goddb.ErrConditionFailed)
+	assert.ErrorAs(t, err, &canceled)
+}
+
+func TestTransactionCanceled(t *testing.T) {
+	type Product struct {
+		ID    string `goddb:"PK,SK"`
+		Stock int
+	}
+	type Order struct {
+		ID string `goddb:"PK,SK"`
+	}
+	explanation, err := goddb.TransactionWrite().Delete(&Product{ID: "abc"}).Put(&Order{ID: "abc"}).Explain()
+	assert.Equal(t, err, nil)
+	input := explanation.Input.(*dynamodb.TransactWriteItemsInput)
+	assert.NotEqual(t, input.TransactItems[0].Delete, nil)
+	assert.NotEqual(t, input.TransactItems[1].Put, nil)
+
+	canceled := &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("None")},
+		{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed"), Item: map[string]types.AttributeValue{
+			"PK":    &types.AttributeValueMemberS{Value: "Product#abc"},
+			"SK":    &types.AttributeValueMemberS{Value: "Product#abc"},
+			"Stock": &types.AttributeValueMemberN{Value: "0"},
+		}},
+	}}
+	db := newDB(t, goddb.WithClient(&transactionErrorClient{Client: goddbtest.New(), err: canceled}))
+	order, product := &Order{ID: "abc"}, &Product{ID: "abc", Stock: 1}
+	err = goddb.TransactionWrite().In(db).Put(order).Put(product).ReturnOldOnConditionFailure().Exec()
+	assert.ErrorIs(t, err, goddb.ErrTransactionCanceled)
+	assert.ErrorIs(t, err, goddb.ErrConditionFailed)
+	var e *goddb.TransactionCanceledError
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, e.Reasons, []goddb.CancellationReason{
+		{Value: order, Code: "None"},
+		{Value: product, Code: "ConditionalCheckFailed", Message: "The conditional request failed", Old: &Product{ID: "abc"}},
 	})
+	assert.Equal(t, err.Error(), "goddb transaction write items: transaction canceled [None, ConditionalCheckFailed]")
+
+	canceled.CancellationReasons[1].Item["SK"] = &types.AttributeValueMemberN{Value: "1"}
+	err = goddb.TransactionWrite().In(db).Put(order).Put(product).ReturnOldOnConditionFailure().Exec()
+	assert.ErrorIs(t, err, goddb.ErrTransactionCanceled)
+	assert.ErrorIs(t, err, goddb.ErrConditionFailed)
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, len(e.Reasons), 2)
+	assert.Equal(t, e.Reasons[1].Code, "ConditionalCheckFailed")
+	assert.Equal(t, e.Reasons[1].Old, nil)
+	assert.Contains(t, err.Error(), "decode old item 1")
+}
+
+func TestTransactionWriteConditions(t *testing.T) {
+	type Product struct {
+		ID    string `goddb:"PK,SK"`
+		Stock int
+	}
+	type Order struct {
+		ID        string `goddb:"PK,SK"`
+		ProductID string
+	}
+	type User struct {
+		ID     string `goddb:"PK,SK"`
+		Active bool
+	}
+	assert.Equal(t, goddb.Put(&Product{ID: "abc", Stock: 1}).Exec(), nil)
+	assert.Equal(t, goddb.Put(&User{ID: "abc", Active: true}).Exec(), nil)
+	order := func(id string) *goddb.TransactionWriteRequest {
+		return goddb.TransactionWrite().Write(
+			goddb.Update(&Product{ID: "abc"}).Add(&Product{Stock: -1}).If(goddb.GreaterThanOrEqual(&Product{Stock: 1})),
+			goddb.Put(&Order{ID: id, ProductID: "abc"}).If(goddb.AttributeNotExists(func(o *Order) any { return &o.ID })),
+			goddb.Check(&User{ID: "abc"}, goddb.Equal(&User{Active: true})),
+		)
+	}
+	assert.Equal(t, order("def").Exec(), nil)
+	product, err := goddb.Get(&Product{ID: "abc"}).Consistent().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, product.Stock, 0)
+
+	var old Product
+	err = goddb.TransactionWrite().Write(
+		goddb.Update(&Product{ID: "abc"}).Add(&Product{Stock: -1}).If(goddb.GreaterThanOrEqual(&Product{Stock: 1})).ReturnOldOnConditionFailure(&old),
+		goddb.Put(&Order{ID: "ghi", ProductID: "abc"}),
+	).Exec()
+	assert.ErrorIs(t, err, goddb.ErrConditionFailed)
+	var canceled *goddb.TransactionCanceledError
+	assert.ErrorAs(t, err, &canceled)
+	assert.Equal(t, canceled.Reasons[0].Code, "ConditionalCheckFailed")
+	assert.Equal(t, canceled.Reasons[0].Old, &old)
+	assert.Equal(t, old, Product{ID: "abc"})
+	_, err = goddb.Get(&Order{ID: "ghi"}).Consistent().Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+
+	assert.Equal(t, goddb.Put(&Product{ID: "abc", Stock: 1}).Exec(), nil)
+	assert.ErrorIs(t, order("def").Exec(), goddb.ErrConditionFailed)
+	assert.Equal(t, goddb.Put(&User{ID: "abc"}).Exec(), nil)
+	err = order("jkl").ReturnOldOnConditionFailure().Exec()
+	assert.ErrorAs(t, err, &canceled)
+	assert.Equal(t, canceled.Reasons[2].Code, "ConditionalCheckFailed")
+	assert.Equal(t, canceled.Reasons[2].Old, &User{ID: "abc"})
+
+	assert.Equal(t, goddb.TransactionWrite().Write(goddb.Delete(&Order{ID: "def"}).If(goddb.Equal(&Order{ProductID: "abc"}))).Exec(), nil)
+	_, err = goddb.Get(&Order{ID: "def"}).Consistent().Exec()
+	assert.ErrorIs(t, err, goddb.ErrItemNotFound)
+
+	other := newDB(t)
+	assert.ErrorIs(t, goddb.TransactionWrite().Write(goddb.Put(&Order{ID: "mno"}).In(other)).Exec(), goddb.ErrValidation)
+	assert.ErrorIs(t, goddb.TransactionWrite().Write(goddb.Update(&Product{ID: "abc"}).Add(&Product{Stock: 1}).In(other)).Exec(), goddb.ErrValidation)
+	assert.ErrorIs(t, goddb.TransactionWrite().Write(goddb.Delete(&Order{ID: "mno"}).In(other)).Exec(), goddb.ErrValidation)
+	assert.Equal(t, goddb.TransactionWrite().In(other).Write(goddb.Put(&Order{ID: "mno"}).In(other)).Exec(), nil)
+	_, err = goddb.Get(&Order{ID: "mno"}).In(other).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, goddb.Delete(&Order{ID: "mno"}).In(other).Exec(), nil)
+
+	assert.ErrorIs(t, goddb.TransactionWrite().Exec(), goddb.ErrValidation)
+	many := goddb.TransactionWrite()
+	for i := range 101 {
+		many.Put(&Order{ID: strconv.Itoa(i)})
+	}
+	assert.ErrorIs(t, many.Exec(), goddb.ErrValidation)
+}
+
+func TestIdempotentTransaction(t *testing.T) {
+	type Account struct {
+		ID      string `goddb:"PK,SK"`
+		Balance int
+	}
+	assert.Equal(t, goddb.Put(&Account{ID: "abc", Balance: 10}).Exec(), nil)
+	deposit := func(amount int) *goddb.TransactionWriteRequest {
+		return goddb.TransactionWrite().Write(goddb.Update(&Account{ID: "abc"}).Add(&Account{Balance: amount}))
+	}
+	assert.Equal(t, deposit(5).IdempotentFor("request-1").Exec(), nil)
+	assert.Equal(t, deposit(5).IdempotentFor("request-1").Exec(), nil)
+	account, err := goddb.Get(&Account{ID: "abc"}).Consistent().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, account.Balance, 15)
+
+	err = deposit(6).IdempotentFor("request-1").Exec()
+	assert.ErrorIs(t, err, goddb.ErrIdempotentParameterMismatch)
+	var mismatch *types.IdempotentParameterMismatchException
+	assert.ErrorAs(t, err, &mismatch)
+
+	assert.Equal(t, deposit(5).Idempotent("request-2").Exec(), nil)
+	account, err = goddb.Get(&Account{ID: "abc"}).Consistent().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, account.Balance, 20)
+
+	assert.ErrorIs(t, deposit(5).Idempotent(strings.Repeat("a", 37)).Exec(), goddb.ErrValidation)
+}
+
+func TestQueryWhere(t *testing.T) {
+	type Post struct {
+		ID        string `goddb:"SK"`
+		Author    string `goddb:"PK"`
+		Published bool
+		Likes     int
+	}
+	type Comment struct {
+		ID    string `goddb:"PK,SK,CommentGSI"`
+		Likes int
+	}
+	for i := range 10 {
+		assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: strconv.Itoa(i), Published: i%3 == 0, Likes: i}).Exec(), nil)
+		assert.Equal(t, goddb.Put(&Comment{ID: strconv.Itoa(i), Likes: i}).Exec(), nil)
+	}
+	posts, err := goddb.Query(&Post{Author: "abc"}).Where(goddb.Equal(&Post{Published: true})).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(posts), 4)
+
+	var offset string
+	var ids []string
+	for {
+		posts, err := goddb.Query(&Post{Author: "abc"}).Where(goddb.Equal(&Post{Published: true})).Page(3, &offset).Exec()
+		assert.Equal(t, err, nil)
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		if offset == "" {
+			break
+		}
+		assert.Equal(t, len(posts), 3)
+	}
+	assert.Equal(t, ids, []string{"0", "3", "6", "9"})
+
+	comments, err := goddb.Query(&Comment{}).Where(goddb.GreaterThan(&Comment{Likes: 6})).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(comments), 3)
+	offset = ""
+	comments, err = goddb.Query(&Comment{}).Where(goddb.LessThan(&Comment{Likes: 3})).Page(2, &offset).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(comments), 2)
+
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "abc"}).Exec(), nil)
+	for i := range 10 {
+		assert.Equal(t, goddb.Delete(&Comment{ID: strconv.Itoa(i)}).Exec(), nil)
+	}
+}
+
+func TestQueryDesc(t *testing.T) {
+	type Post struct {
+		ID     string `goddb:"SK"`
+		Author string `goddb:"PK"`
+	}
+	for i := range 5 {
+		assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: strconv.Itoa(i)}).Exec(), nil)
+	}
+	ids := func(posts []*Post) []string {
+		var ids []string
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+	posts, err := goddb.Query(&Post{Author: "abc"}).Desc().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(posts), []string{"4", "3", "2", "1", "0"})
+
+	var offset string
+	posts, err = goddb.Query(&Post{Author: "abc"}).Desc().Page(2, &offset).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(posts), []string{"4", "3"})
+	posts, err = goddb.Query(&Post{Author: "abc"}).Desc().Page(2, &offset).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(posts), []string{"2", "1"})
+
+	posts, err = goddb.Query(&Post{Author: "abc"}).Between(&Post{ID: "1"}, &Post{ID: "3"}).Desc().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(posts), []string{"3", "2", "1"})
+	posts, err = goddb.Query(&Post{Author: "abc"}).BeginsWith(&Post{ID: "2"}).Desc().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(posts), []string{"2"})
+
+	type Comment struct {
+		ID string `goddb:"PK,SK,CommentGSI"`
+	}
+	_, err = goddb.Query(&Comment{}).Desc().Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "abc"}).Exec(), nil)
+}
+
+func TestQueryIter(t *testing.T) {
+	type Post struct {
+		ID       string `goddb:"SK,GSI1SK"`
+		Author   string `goddb:"PK"`
+		Category string `goddb:"GSI1PK"`
+		Title    string
+	}
+	type Comment struct {
+		ID string `goddb:"PK,SK,CommentGSI"`
+	}
+	for i := range 7 {
+		assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: strconv.Itoa(i), Category: "foo", Title: "t" + strconv.Itoa(i)}).Exec(), nil)
+		assert.Equal(t, goddb.Put(&Comment{ID: strconv.Itoa(i)}).Exec(), nil)
+	}
+	var ids []string
+	for post, err := range goddb.Query(&Post{Author: "abc"}).All() {
+		assert.Equal(t, err, nil)
+		ids = append(ids, post.ID)
+	}
+	assert.Equal(t, ids, []string{"0", "1", "2", "3", "4", "5", "6"})
+
+	var stats goddb.Stats
+	var offset string
+	ids = nil
+	for post, err := range goddb.Query(&Post{Category: "foo"}).Page(2, &offset).Stats(&stats).Iter(context.Background()) {
+		assert.Equal(t, err, nil)
+		ids = append(ids, post.ID)
+		if len(ids) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, ids, []string{"0", "1", "2"})
+	assert.Equal(t, stats.Pages, 2)
+	for post, err := range goddb.Query(&Post{Category: "foo"}).Page(2, &offset).Iter(context.Background()) {
+		assert.Equal(t, err, nil)
+		ids = append(ids, post.ID)
+	}
+	assert.Equal(t, ids, []string{"0", "1", "2", "3", "4", "5", "6"})
+	assert.Equal(t, offset, "")
+
+	var titles []string
+	for post, err := range goddb.Query(&Post{Category: "foo"}).Select(func(p *Post) any { return p.Title }).Page(3, &offset).All() {
+		assert.Equal(t, err, nil)
+		titles = append(titles, post.Title)
+		break
+	}
+	for post, err := range goddb.Query(&Post{Category: "foo"}).Select(func(p *Post) any { return p.Title }).Page(3, &offset).All() {
+		assert.Equal(t, err, nil)
+		titles = append(titles, post.Title)
+	}
+	assert.Equal(t, titles, []string{"t0", "t1", "t2", "t3", "t4", "t5", "t6"})
+
+	offset = ""
+	var comments int
+	for _, err := range goddb.Query(&Comment{}).Page(3, &offset).All() {
+		assert.Equal(t, err, nil)
+		comments++
+		if comments == 4 {
+			break
+		}
+	}
+	for _, err := range goddb.Query(&Comment{}).Page(3, &offset).All() {
+		assert.Equal(t, err, nil)
+		comments++
+	}
+	assert.Equal(t, comments, 7)
+
+	for comment, err := range goddb.Query(&Comment{ID: "a#b"}).All() {
+		assert.Equal(t, comment, (*Comment)(nil))
+		assert.ErrorIs(t, err, goddb.ErrValidation)
+		comments++
+	}
+	assert.Equal(t, comments, 8)
+	assert.Equal(t, goddb.DeleteAll(&Post{Author: "abc"}).Exec(), nil)
+	for i := range 7 {
+		assert.Equal(t, goddb.Delete(&Comment{ID: strconv.Itoa(i)}).Exec(), nil)
+	}
+}
+
+func TestQueryCount(t *testing.T) {
+	type Post struct {
+		ID        string `goddb:"SK"`
+		Author    string `goddb:"PK"`
+		Published bool
+	}
+	type Comment struct {
+		ID string `goddb:"PK,SK,CommentGSI"`
+	}
+	db, err := goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(goddbtest.New(goddbtest.WithPageSize(3))))
+	assert.Equal(t, err, nil)
+	for i := range 10 {
+		assert.Equal(t, goddb.Put(&Post{Author: "abc", ID: strconv.Itoa(i), Published: i%2 == 0}).In(db).Exec(), nil)
+		assert.Equal(t, goddb.Put(&Comment{ID: strconv.Itoa(i)}).In(db).Exec(), nil)
+	}
+	var stats goddb.Stats
+	count, scanned, err := goddb.Query(&Post{Author: "abc"}).In(db).Stats(&stats).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 10)
+	assert.Equal(t, scanned, 10)
+	assert.Equal(t, stats.Pages, 4)
+
+	count, scanned, err = goddb.Query(&Post{Author: "abc"}).In(db).Where(goddb.Equal(&Post{Published: true})).Select(func(p *Post) any { return p.Published }).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 5)
+	assert.Equal(t, scanned, 10)
+
+	count, _, err = goddb.Query(&Post{Author: "abc"}).In(db).Between(&Post{ID: "2"}, &Post{ID: "5"}).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 4)
+	count, _, err = goddb.Query(&Post{Author: "abc"}).In(db).BeginsWith(&Post{ID: "7"}).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	count, _, err = goddb.Query(&Comment{}).In(db).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 10)
+}
+
+func TestQueryAfterBefore(t *testing.T) {
+	type Event struct {
+		User string    `goddb:"PK"`
+		At   time.Time `goddb:"SK"`
+	}
+	type Note struct {
+		User string `goddb:"PK"`
+		ID   string `goddb:"SK"`
+	}
+	type Alert struct {
+		User string `goddb:"PK"`
+		ID   string `goddb:"SK"`
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		assert.Equal(t, goddb.Put(&Event{User: "abc", At: start.Add(time.Duration(i) * time.Hour)}).Exec(), nil)
+	}
+	assert.Equal(t, goddb.Put(&Note{User: "abc", ID: "abc"}).Exec(), nil)
+	assert.Equal(t, goddb.Put(&Alert{User: "abc", ID: "abc"}).Exec(), nil)
+	hours := func(events []*Event) []int {
+		var hours []int
+		for _, event := range events {
+			hours = append(hours, int(event.At.Sub(start).Hours()))
+		}
+		return hours
+	}
+	at := func(hour int) *Event {
+		return &Event{At: start.Add(time.Duration(hour) * time.Hour)}
+	}
+	events, err := goddb.Query(&Event{User: "abc"}).After(at(2)).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{3, 4})
+	events, err = goddb.Query(&Event{User: "abc"}).AfterOrEqual(at(2)).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{2, 3, 4})
+	events, err = goddb.Query(&Event{User: "abc"}).Before(at(2)).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{0, 1})
+	events, err = goddb.Query(&Event{User: "abc"}).BeforeOrEqual(at(2)).Desc().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{2, 1, 0})
+	events, err = goddb.Query(&Event{User: "abc"}).After(at(0)).Before(at(4)).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{1, 2, 3})
+	notes, err := goddb.Query(&Note{User: "abc"}).After(&Note{ID: "aaa"}).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(notes), 1)
+	events, err = goddb.Query(&Event{User: "abc"}).After(at(2)).Desc().Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{4, 3})
+	events, err = goddb.Query(&Event{User: "abc"}).AfterOrEqual(at(1)).BeforeOrEqual(at(3)).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hours(events), []int{1, 2, 3})
+	var offset string
+	events = nil
+	for {
+		page, err := goddb.Query(&Event{User: "abc"}).After(at(0)).Page(2, &offset).Exec()
+		assert.Equal(t, err, nil)
+		events = append(events, page...)
+		if offset == "" {
+			break
+		}
+	}
+	assert.Equal(t, hours(events), []int{1, 2, 3, 4})
+	count, _, err := goddb.Query(&Event{User: "abc"}).Before(at(3)).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 3)
+	count, _, err = goddb.Query(&Event{User: "abc"}).After(at(0)).Before(at(4)).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 3)
+	notes, err = goddb.Query(&Note{User: "abc"}).Before(&Note{ID: "zzz"}).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(notes), 1)
+	for condition, query := range map[string]*goddb.QueryRequest[Event]{
+		"#pk = :pk and #sk > :start":                goddb.Query(&Event{User: "abc"}).After(at(0)),
+		"#pk = :pk and #sk >= :start":               goddb.Query(&Event{User: "abc"}).AfterOrEqual(at(0)),
+		"#pk = :pk and #sk < :end":                  goddb.Query(&Event{User: "abc"}).Before(at(0)),
+		"#pk = :pk and #sk <= :end":                 goddb.Query(&Event{User: "abc"}).BeforeOrEqual(at(0)),
+		"#pk = :pk and #sk between :start and :end": goddb.Query(&Event{User: "abc"}).After(at(0)).Before(at(4)),
+	} {
+		explanation, err := query.Explain()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, *explanation.Input.(*dynamodb.QueryInput).KeyConditionExpression, condition)
+	}
+	_, err = goddb.Query(&Event{User: "abc"}).BeginsWith(at(0)).After(at(0)).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	_, err = goddb.Query(&Event{User: "abc"}).Before(at(4)).Between(at(0), at(2)).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	_, err = goddb.Query(&Event{User: "abc"}).BeginsWith(at(0)).Between(at(0), at(2)).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	assert.Equal(t, goddb.DeleteAll(&Event{User: "abc"}).Exec(), nil)
+	assert.Equal(t, goddb.DeleteAll(&Note{User: "abc"}).Exec(), nil)
+	assert.Equal(t, goddb.DeleteAll(&Alert{User: "abc"}).Exec(), nil)
+}
+
+func TestQuerySimpleGSI(t *testing.T) {
+	type Order struct {
+		ID     string `goddb:"PK,SK"`
+		Status string `goddb:"OrderGSI"`
+	}
+	for i := range 6 {
+		status := "pending"
+		if i%3 == 0 {
+			status = "shipped"
+		}
+		assert.Equal(t, goddb.Put(&Order{ID: strconv.Itoa(i), Status: status}).Exec(), nil)
+	}
+	var stats goddb.Stats
+	orders, err := goddb.Query(&Order{Status: "pending"}).Stats(&stats).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(orders), 4)
+	for _, order := range orders {
+		assert.Equal(t, order.Status, "pending")
+	}
+	order, err := goddb.Get(&Order{ID: "0"}).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, order, &Order{ID: "0", Status: "shipped"})
+	assert.Equal(t, stats.ScannedCount, 4)
+	explanation, err := goddb.Query(&Order{Status: "pending"}).Explain()
+	assert.Equal(t, err, nil)
+	input := explanation.Input.(*dynamodb.QueryInput)
+	assert.Equal(t, *input.IndexName, "OrderGSI")
+	assert.Equal(t, *input.KeyConditionExpression, "#pk = :pk")
+
+	var offset string
+	var ids []string
+	for range 3 {
+		orders, err = goddb.Query(&Order{Status: "pending"}).Page(2, &offset).Exec()
+		assert.Equal(t, err, nil)
+		for _, order := range orders {
+			ids = append(ids, order.ID)
+		}
+	}
+	assert.Equal(t, len(ids), 4)
+	assert.Equal(t, offset, "")
+	for order, err := range goddb.Query(&Order{Status: "pending"}).Page(3, &offset).All() {
+		assert.Equal(t, err, nil)
+		ids = append(ids, order.ID)
+		break
+	}
+	for order, err := range goddb.Query(&Order{Status: "pending"}).Page(3, &offset).All() {
+		assert.Equal(t, err, nil)
+		ids = append(ids, order.ID)
+	}
+	slices.Sort(ids)
+	assert.Equal(t, ids, []string{"1", "1", "2", "2", "4", "4", "5", "5"})
+	count, _, err := goddb.Query(&Order{Status: "shipped"}).Count()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 2)
+
+	explanation, err = goddb.Query(&Order{}).Explain()
+	assert.Equal(t, err, nil)
+	_, ok := explanation.Input.(*dynamodb.ScanInput)
+	assert.Equal(t, ok, true)
+	all, err := goddb.Query(&Order{}).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(all), 6)
+	for _, order := range all {
+		assert.NotEqual(t, order.Status, "")
+	}
+	_, err = goddb.Query(&Order{Status: "pending"}).BeginsWith(&Order{ID: "1"}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	_, err = goddb.Query(&Order{Status: "pending"}).After(&Order{ID: "1"}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	_, err = goddb.Query(&Order{}).Between(&Order{ID: "1"}, &Order{ID: "3"}).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	for i := range 6 {
+		assert.Equal(t, goddb.Delete(&Order{ID: strconv.Itoa(i)}).Exec(), nil)
+	}
+}
+
+func TestQueryParallel(t *testing.T) {
+	type User struct {
+		ID string `goddb:"PK,SK,UserGSI"`
+	}
+	db, err := goddb.New(goddb.WithTableName("goddb"), goddb.WithClient(goddbtest.New(goddbtest.WithPageSize(3))))
+	assert.Equal(t, err, nil)
+	for i := range 20 {
+		assert.Equal(t, goddb.Put(&User{ID: strconv.Itoa(i)}).In(db).Exec(), nil)
+	}
+	ids := func(users []*User) []string {
+		var ids []string
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	all, err := goddb.Query(&User{}).In(db).Exec()
+	assert.Equal(t, err, nil)
+	users, err := goddb.Query(&User{}).In(db).Parallel(4).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(users), ids(all))
+	assert.Equal(t, len(users), 20)
+
+	var offset string
+	users = nil
+	for {
+		page, err := goddb.Query(&User{}).In(db).Parallel(4).Page(2, &offset).Exec()
+		assert.Equal(t, err, nil)
+		users = append(users, page...)
+		if offset == "" {
+			break
+		}
+	}
+	assert.Equal(t, ids(users), ids(all))
+
+	users = nil
+	for user, err := range goddb.Query(&User{}).In(db).Parallel(4).Page(5, &offset).All() {
+		assert.Equal(t, err, nil)
+		users = append(users, user)
+		if len(users) == 7 {
+			break
+		}
+	}
+	assert.NotEqual(t, offset, "")
+	_, err = goddb.Query(&User{}).In(db).Parallel(3).Page(5, &offset).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+	for user, err := range goddb.Query(&User{}).In(db).Parallel(4).Page(5, &offset).All() {
+		assert.Equal(t, err, nil)
+		users = append(users, user)
+	}
+	assert.Equal(t, ids(users), ids(all))
+	assert.Equal(t, offset, "")
+
+	_, err = goddb.Query(&User{ID: "1"}).In(db).Parallel(4).Exec()
+	assert.ErrorIs(t, err, goddb.ErrValidation)
+
+	type Member struct {
+		ID     string `goddb:"PK,SK"`
+		Team   string `goddb:"MemberGSI"`
+		Name   string
+		Active bool
+	}
+	for i := range 20 {
+		assert.Equal(t, goddb.Put(&Member{ID: strconv.Itoa(i), Team: "a", Name: "n" + strconv.Itoa(i), Active: i%5 == 0}).In(db).Exec(), nil)
+	}
+	members, err := goddb.Query(&Member{}).In(db).Parallel(4).Where(goddb.Equal(&Member{Active: true})).Page(2, nil).Exec()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(members), 2)
+	var active []*Member
+	for {
+		page, err := goddb.Query(&Member{}).In(db).Parallel(4).Where(goddb.Equal(&Member{Active: true})).Page(3, &offset).Exec()
+		assert.Equal(t, err, nil)
+		assert.LessOrEqual(t, len(page), 3)
+		active = append(active, page...)
+		if offset == "" {
+			break
+		}
+	}
+	assert.Equal(t, len(active), 4)
+	var names []string
+	for member, err := range goddb.Query(&Member{}).In(db).Parallel(4).Select(func(m *Member) any { return m.Name }).Page(5, &offset).All() {
+		assert.Equal(t, err, nil)
+		names = append(names, member.Name)
+		if len(names) == 7 {
+			break
+		}
+	}
+	assert.NotEqual(t, offset, "")
+	for member, err := range goddb.Query(&Member{}).In(db).Parallel(4).Select(func(m *Member) any { return m.Name }).Page(5, &offset).All() {
+		assert.Equal(t, err, nil)
+		names = append(names, member.Name)
+	}
+	assert.Equal(t, len(names), 20)
+	slices.Sort(names)
+	assert.Equal(t, slices.Compact(names), names)
+	assert.Equal(t, offset, "")
 }
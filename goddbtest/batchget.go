@@ -0,0 +1,84 @@
+package goddbtest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxBatchGetKeys = 100
+
+func (s *Store) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("BatchGetItem", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys int
+	for _, req := range params.RequestItems {
+		keys += len(req.Keys)
+	}
+	if keys == 0 || keys > maxBatchGetKeys {
+		return nil, operationError("BatchGetItem", validationError("too many items requested for the BatchGetItem call"))
+	}
+	output := &dynamodb.BatchGetItemOutput{
+		Responses: make(map[string][]map[string]types.AttributeValue),
+	}
+	var processed int
+	for name, req := range params.RequestItems {
+		t, err := s.table(aws.String(name))
+		if err != nil {
+			return nil, operationError("BatchGetItem", err)
+		}
+		ctx := newExprContext(req.ExpressionAttributeNames, nil)
+		var projection []string
+		if req.ProjectionExpression != nil {
+			projection, err = parseProjection(*req.ProjectionExpression, ctx)
+			if err != nil {
+				return nil, operationError("BatchGetItem", validationError("invalid ProjectionExpression: %s", err))
+			}
+		}
+		if err := ctx.checkUnused(); err != nil {
+			return nil, operationError("BatchGetItem", validationError("%s", err))
+		}
+		seen := make(map[string]bool)
+		var bytes int
+		for _, key := range req.Keys {
+			if err := s.validateKey(key); err != nil {
+				return nil, operationError("BatchGetItem", err)
+			}
+			k := s.key(key)
+			if seen[k] {
+				return nil, operationError("BatchGetItem", validationError("provided list of item keys contains duplicates"))
+			}
+			seen[k] = true
+		}
+		var unprocessed []map[string]types.AttributeValue
+		for _, key := range req.Keys {
+			if s.batchSize > 0 && processed == s.batchSize {
+				unprocessed = append(unprocessed, key)
+				continue
+			}
+			processed++
+			item, ok := t.items[s.key(key)]
+			bytes += itemSize(item)
+			if ok {
+				output.Responses[name] = append(output.Responses[name], project(item, projection))
+			}
+		}
+		if unprocessed != nil {
+			if output.UnprocessedKeys == nil {
+				output.UnprocessedKeys = make(map[string]types.KeysAndAttributes)
+			}
+			rest := req
+			rest.Keys = unprocessed
+			output.UnprocessedKeys[name] = rest
+		}
+		if c := consumed(params.ReturnConsumedCapacity, aws.String(name), "", readUnits(bytes, aws.ToBool(req.ConsistentRead)), 0); c != nil {
+			output.ConsumedCapacity = append(output.ConsumedCapacity, *c)
+		}
+	}
+	return output, nil
+}
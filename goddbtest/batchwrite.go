@@ -0,0 +1,84 @@
+package goddbtest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxBatchWriteItems = 25
+
+func (s *Store) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("BatchWriteItem", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, reqs := range params.RequestItems {
+		n += len(reqs)
+	}
+	if n == 0 || n > maxBatchWriteItems {
+		return nil, operationError("BatchWriteItem", validationError("1 validation error detected: value at 'requestItems' failed to satisfy constraint: member must have length less than or equal to %d", maxBatchWriteItems))
+	}
+	type pending struct {
+		table string
+		req   types.WriteRequest
+		w     *write
+	}
+	var writes []pending
+	seen := make(map[*table]map[string]bool)
+	for name, reqs := range params.RequestItems {
+		for _, req := range reqs {
+			var w *write
+			var err error
+			switch {
+			case req.PutRequest != nil && req.DeleteRequest == nil:
+				w, err = s.preparePut(aws.String(name), req.PutRequest.Item, nil, nil, nil)
+			case req.DeleteRequest != nil && req.PutRequest == nil:
+				w, err = s.prepareDelete(aws.String(name), req.DeleteRequest.Key, nil, nil, nil)
+			default:
+				err = validationError("write request must specify exactly one of PutRequest or DeleteRequest")
+			}
+			if err != nil {
+				return nil, operationError("BatchWriteItem", err)
+			}
+			if seen[w.table] == nil {
+				seen[w.table] = make(map[string]bool)
+			}
+			if seen[w.table][w.key] {
+				return nil, operationError("BatchWriteItem", validationError("provided list of item keys contains duplicates"))
+			}
+			seen[w.table][w.key] = true
+			writes = append(writes, pending{table: name, req: req, w: w})
+		}
+	}
+	output := &dynamodb.BatchWriteItemOutput{}
+	units := make(map[string]float64)
+	var tables []string
+	for i, p := range writes {
+		if s.batchSize > 0 && i >= s.batchSize {
+			if output.UnprocessedItems == nil {
+				output.UnprocessedItems = make(map[string][]types.WriteRequest)
+			}
+			output.UnprocessedItems[p.table] = append(output.UnprocessedItems[p.table], p.req)
+			continue
+		}
+		old, item, _, err := s.commit(p.w, "")
+		if err != nil {
+			return nil, operationError("BatchWriteItem", err)
+		}
+		if _, ok := units[p.table]; !ok {
+			tables = append(tables, p.table)
+		}
+		units[p.table] += writeUnits(max(itemSize(old), itemSize(item)))
+	}
+	for _, name := range tables {
+		if c := consumed(params.ReturnConsumedCapacity, aws.String(name), "", 0, units[name]); c != nil {
+			output.ConsumedCapacity = append(output.ConsumedCapacity, *c)
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,53 @@
+package goddbtest
+
+import (
+	"math"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// readUnits returns the read capacity units used to read the given number of
+// bytes. Eventually consistent reads cost half as much.
+func readUnits(bytes int, consistent bool) float64 {
+	units := math.Max(1, math.Ceil(float64(bytes)/4096))
+	if !consistent {
+		return units / 2
+	}
+	return units
+}
+
+// writeUnits returns the write capacity units used to write an item of the
+// given size.
+func writeUnits(bytes int) float64 {
+	return math.Max(1, math.Ceil(float64(bytes)/1024))
+}
+
+// consumed returns the capacity used on a table, or on one of its global
+// secondary indexes if index is not empty, in the detail asked for by mode.
+func consumed(mode types.ReturnConsumedCapacity, tableName *string, index string, read, write float64) *types.ConsumedCapacity {
+	if mode != types.ReturnConsumedCapacityTotal && mode != types.ReturnConsumedCapacityIndexes {
+		return nil
+	}
+	units := types.Capacity{CapacityUnits: aws.Float64(read + write)}
+	if read > 0 {
+		units.ReadCapacityUnits = aws.Float64(read)
+	}
+	if write > 0 {
+		units.WriteCapacityUnits = aws.Float64(write)
+	}
+	c := &types.ConsumedCapacity{
+		TableName:          tableName,
+		CapacityUnits:      units.CapacityUnits,
+		ReadCapacityUnits:  units.ReadCapacityUnits,
+		WriteCapacityUnits: units.WriteCapacityUnits,
+	}
+	if mode == types.ReturnConsumedCapacityIndexes {
+		if index == "" {
+			c.Table = &units
+		} else {
+			c.GlobalSecondaryIndexes = map[string]types.Capacity{index: units}
+		}
+	}
+	return c
+}
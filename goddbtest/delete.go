@@ -0,0 +1,36 @@
+package goddbtest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func (s *Store) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("DeleteItem", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, err := s.prepareDelete(params.TableName, params.Key, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+	switch params.ReturnValues {
+	case "", types.ReturnValueNone, types.ReturnValueAllOld:
+	default:
+		return nil, operationError("DeleteItem", validationError("return values %s is not valid for DeleteItem", params.ReturnValues))
+	}
+	old, _, _, err := s.commit(w, params.ReturnValuesOnConditionCheckFailure)
+	if err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+	output := &dynamodb.DeleteItemOutput{
+		ConsumedCapacity: consumed(params.ReturnConsumedCapacity, params.TableName, "", 0, writeUnits(itemSize(old))),
+	}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+	return output, nil
+}
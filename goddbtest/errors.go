@@ -0,0 +1,29 @@
+package goddbtest
+
+import (
+	"fmt"
+
+	"github.com/aws/smithy-go"
+)
+
+func validationError(format string, args ...any) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+// operationError wraps err the way the AWS SDK does, so callers can unwrap
+// it with errors.As just like errors from a real client.
+func operationError(operation string, err error) error {
+	return &smithy.OperationError{
+		ServiceID:     "DynamoDB",
+		OperationName: operation,
+		Err:           err,
+	}
+}
+
+func canceled(operation string, err error) error {
+	return operationError(operation, &smithy.CanceledError{Err: err})
+}
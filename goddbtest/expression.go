@@ -0,0 +1,627 @@
+package goddbtest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenName
+	tokenValue
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func isIdentRune(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func lex(expr string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':':
+			j := i + 1
+			for j < len(expr) && isIdentRune(expr[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid expression: syntax error near %q", expr[i:])
+			}
+			kind := tokenName
+			if c == ':' {
+				kind = tokenValue
+			}
+			toks = append(toks, token{kind: kind, text: expr[i:j]})
+			i = j
+		case isIdentRune(c):
+			j := i
+			for j < len(expr) && isIdentRune(expr[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tokenIdent, text: expr[i:j]})
+			i = j
+		case c == '<' || c == '>':
+			if i+1 < len(expr) && (expr[i+1] == '=' || c == '<' && expr[i+1] == '>') {
+				toks = append(toks, token{kind: tokenPunct, text: expr[i : i+2]})
+				i += 2
+				continue
+			}
+			toks = append(toks, token{kind: tokenPunct, text: expr[i : i+1]})
+			i++
+		case strings.ContainsRune("(),=+-.[]", rune(c)):
+			toks = append(toks, token{kind: tokenPunct, text: expr[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("invalid expression: unexpected character %q", c)
+		}
+	}
+	return append(toks, token{kind: tokenEOF}), nil
+}
+
+// exprContext resolves expression attribute names and values shared by all
+// expressions of a request and records which of them were used.
+type exprContext struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newExprContext(names map[string]string, values map[string]types.AttributeValue) *exprContext {
+	return &exprContext{
+		names:      names,
+		values:     values,
+		usedNames:  make(map[string]bool),
+		usedValues: make(map[string]bool),
+	}
+}
+
+func (c *exprContext) name(placeholder string) (string, error) {
+	name, ok := c.names[placeholder]
+	if !ok {
+		return "", fmt.Errorf("invalid expression: an expression attribute name used in the document path is not defined; attribute name: %s", placeholder)
+	}
+	c.usedNames[placeholder] = true
+	return name, nil
+}
+
+func (c *exprContext) value(placeholder string) (types.AttributeValue, error) {
+	v, ok := c.values[placeholder]
+	if !ok {
+		return nil, fmt.Errorf("invalid expression: an expression attribute value used in expression is not defined; attribute value: %s", placeholder)
+	}
+	c.usedValues[placeholder] = true
+	return v, nil
+}
+
+func (c *exprContext) checkUnused() error {
+	for k := range c.names {
+		if !c.usedNames[k] {
+			return fmt.Errorf("value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", k)
+		}
+	}
+	for k := range c.values {
+		if !c.usedValues[k] {
+			return fmt.Errorf("value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", k)
+		}
+	}
+	return nil
+}
+
+type parser struct {
+	toks  []token
+	pos   int
+	ctx   *exprContext
+	attrs []string
+}
+
+func newParser(expr string, ctx *exprContext) (*parser, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{toks: toks, ctx: ctx}, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(punct string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.accept(punct) {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokenIdent && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// function reports whether the next tokens are a call of the named function.
+func (p *parser) function(name string) bool {
+	if t := p.peek(); t.kind == tokenIdent && t.text == name && p.toks[p.pos+1].text == "(" {
+		p.pos += 2
+		return true
+	}
+	return false
+}
+
+func (p *parser) syntaxError() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("invalid expression: syntax error; unexpected end of expression")
+	}
+	return fmt.Errorf("invalid expression: syntax error; token: %q", t.text)
+}
+
+func (p *parser) path() (string, error) {
+	t := p.next()
+	var attr string
+	switch t.kind {
+	case tokenName:
+		name, err := p.ctx.name(t.text)
+		if err != nil {
+			return "", err
+		}
+		attr = name
+	case tokenIdent:
+		attr = t.text
+	default:
+		p.pos--
+		return "", p.syntaxError()
+	}
+	if n := p.peek(); n.text == "." || n.text == "[" {
+		return "", fmt.Errorf("invalid expression: nested document paths are not supported by goddbtest")
+	}
+	p.attrs = append(p.attrs, attr)
+	return attr, nil
+}
+
+type operand interface {
+	resolve(item map[string]types.AttributeValue) (types.AttributeValue, bool)
+}
+
+type pathOperand string
+
+func (o pathOperand) resolve(item map[string]types.AttributeValue) (types.AttributeValue, bool) {
+	v, ok := item[string(o)]
+	return v, ok
+}
+
+type valueOperand struct {
+	value types.AttributeValue
+}
+
+func (o valueOperand) resolve(map[string]types.AttributeValue) (types.AttributeValue, bool) {
+	return o.value, true
+}
+
+type sizeOperand string
+
+func (o sizeOperand) resolve(item map[string]types.AttributeValue) (types.AttributeValue, bool) {
+	v, ok := item[string(o)]
+	if !ok {
+		return nil, false
+	}
+	var n int
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		n = len(v.Value)
+	case *types.AttributeValueMemberB:
+		n = len(v.Value)
+	case *types.AttributeValueMemberSS:
+		n = len(v.Value)
+	case *types.AttributeValueMemberNS:
+		n = len(v.Value)
+	case *types.AttributeValueMemberBS:
+		n = len(v.Value)
+	case *types.AttributeValueMemberL:
+		n = len(v.Value)
+	case *types.AttributeValueMemberM:
+		n = len(v.Value)
+	default:
+		return nil, false
+	}
+	return &types.AttributeValueMemberN{Value: fmt.Sprint(n)}, true
+}
+
+func (p *parser) operand() (operand, error) {
+	if p.function("size") {
+		attr, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return sizeOperand(attr), nil
+	}
+	if t := p.peek(); t.kind == tokenValue {
+		p.pos++
+		v, err := p.ctx.value(t.text)
+		if err != nil {
+			return nil, err
+		}
+		return valueOperand{value: v}, nil
+	}
+	attr, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return pathOperand(attr), nil
+}
+
+type condition interface {
+	eval(item map[string]types.AttributeValue) bool
+}
+
+type andCondition struct {
+	left, right condition
+}
+
+func (c andCondition) eval(item map[string]types.AttributeValue) bool {
+	return c.left.eval(item) && c.right.eval(item)
+}
+
+type orCondition struct {
+	left, right condition
+}
+
+func (c orCondition) eval(item map[string]types.AttributeValue) bool {
+	return c.left.eval(item) || c.right.eval(item)
+}
+
+type notCondition struct {
+	cond condition
+}
+
+func (c notCondition) eval(item map[string]types.AttributeValue) bool {
+	return !c.cond.eval(item)
+}
+
+type compareCondition struct {
+	op          string
+	left, right operand
+}
+
+func (c compareCondition) eval(item map[string]types.AttributeValue) bool {
+	l, lok := c.left.resolve(item)
+	r, rok := c.right.resolve(item)
+	if !lok || !rok {
+		return c.op == "<>"
+	}
+	switch c.op {
+	case "=":
+		return equal(l, r)
+	case "<>":
+		return !equal(l, r)
+	}
+	cmp, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+type betweenCondition struct {
+	value, low, high operand
+}
+
+func (c betweenCondition) eval(item map[string]types.AttributeValue) bool {
+	v, ok := c.value.resolve(item)
+	if !ok {
+		return false
+	}
+	lo, ok := c.low.resolve(item)
+	if !ok {
+		return false
+	}
+	hi, ok := c.high.resolve(item)
+	if !ok {
+		return false
+	}
+	a, ok := compare(v, lo)
+	if !ok || a < 0 {
+		return false
+	}
+	b, ok := compare(v, hi)
+	return ok && b <= 0
+}
+
+type inCondition struct {
+	value operand
+	list  []operand
+}
+
+func (c inCondition) eval(item map[string]types.AttributeValue) bool {
+	v, ok := c.value.resolve(item)
+	if !ok {
+		return false
+	}
+	for _, o := range c.list {
+		if w, ok := o.resolve(item); ok && equal(v, w) {
+			return true
+		}
+	}
+	return false
+}
+
+type functionCondition struct {
+	name string
+	path string
+	arg  operand
+}
+
+func (c functionCondition) eval(item map[string]types.AttributeValue) bool {
+	v, exists := item[c.path]
+	switch c.name {
+	case "attribute_exists":
+		return exists
+	case "attribute_not_exists":
+		return !exists
+	}
+	if !exists {
+		return false
+	}
+	arg, ok := c.arg.resolve(item)
+	if !ok {
+		return false
+	}
+	switch c.name {
+	case "attribute_type":
+		t, ok := arg.(*types.AttributeValueMemberS)
+		return ok && typeOf(v) == t.Value
+	case "begins_with":
+		switch v := v.(type) {
+		case *types.AttributeValueMemberS:
+			prefix, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.HasPrefix(v.Value, prefix.Value)
+		case *types.AttributeValueMemberB:
+			prefix, ok := arg.(*types.AttributeValueMemberB)
+			return ok && len(v.Value) >= len(prefix.Value) && string(v.Value[:len(prefix.Value)]) == string(prefix.Value)
+		}
+	case "contains":
+		switch v := v.(type) {
+		case *types.AttributeValueMemberS:
+			sub, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.Contains(v.Value, sub.Value)
+		case *types.AttributeValueMemberSS:
+			e, ok := arg.(*types.AttributeValueMemberS)
+			return ok && slices.Contains(v.Value, e.Value)
+		case *types.AttributeValueMemberNS:
+			e, ok := arg.(*types.AttributeValueMemberN)
+			return ok && slices.ContainsFunc(v.Value, func(n string) bool { return numbersEqual(n, e.Value) })
+		case *types.AttributeValueMemberBS:
+			e, ok := arg.(*types.AttributeValueMemberB)
+			return ok && slices.ContainsFunc(v.Value, func(b []byte) bool { return string(b) == string(e.Value) })
+		case *types.AttributeValueMemberL:
+			return slices.ContainsFunc(v.Value, func(e types.AttributeValue) bool { return equal(e, arg) })
+		}
+	}
+	return false
+}
+
+// parseCondition parses condition, filter and key condition expressions. It
+// returns the condition and the attributes the expression refers to.
+func parseCondition(expr string, ctx *exprContext) (condition, []string, error) {
+	p, err := newParser(expr, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cond, err := p.or()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, nil, p.syntaxError()
+	}
+	return cond, p.attrs, nil
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if p.keyword("not") {
+		cond, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{cond: cond}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.accept("(") {
+		cond, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+	for _, name := range []string{"attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains"} {
+		if !p.function(name) {
+			continue
+		}
+		attr, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		cond := functionCondition{name: name, path: attr}
+		if name != "attribute_exists" && name != "attribute_not_exists" {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			cond.arg, err = p.operand()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("between") {
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("and") {
+			return nil, p.syntaxError()
+		}
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{value: left, low: low, high: high}, nil
+	}
+	if p.keyword("in") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond := inCondition{value: left}
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			cond.list = append(cond.list, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+	t := p.next()
+	switch t.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		p.pos--
+		return nil, p.syntaxError()
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return compareCondition{op: t.text, left: left, right: right}, nil
+}
+
+func parseProjection(expr string, ctx *exprContext) ([]string, error) {
+	p, err := newParser(expr, ctx)
+	if err != nil {
+		return nil, err
+	}
+	var attrs []string
+	for {
+		attr, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.syntaxError()
+	}
+	return attrs, nil
+}
+
+func project(item map[string]types.AttributeValue, attrs []string) map[string]types.AttributeValue {
+	if attrs == nil {
+		return copyItem(item)
+	}
+	out := make(map[string]types.AttributeValue)
+	for _, attr := range attrs {
+		if v, ok := item[attr]; ok {
+			out[attr] = copyValue(v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package goddbtest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func (s *Store) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("GetItem", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.table(params.TableName)
+	if err != nil {
+		return nil, operationError("GetItem", err)
+	}
+	if err := s.validateKey(params.Key); err != nil {
+		return nil, operationError("GetItem", err)
+	}
+	exprCtx := newExprContext(params.ExpressionAttributeNames, nil)
+	var projection []string
+	if params.ProjectionExpression != nil {
+		projection, err = parseProjection(*params.ProjectionExpression, exprCtx)
+		if err != nil {
+			return nil, operationError("GetItem", validationError("invalid ProjectionExpression: %s", err))
+		}
+	}
+	if err := exprCtx.checkUnused(); err != nil {
+		return nil, operationError("GetItem", validationError("%s", err))
+	}
+	item, ok := t.items[s.key(params.Key)]
+	output := &dynamodb.GetItemOutput{
+		ConsumedCapacity: consumed(params.ReturnConsumedCapacity, params.TableName, "", readUnits(itemSize(item), aws.ToBool(params.ConsistentRead)), 0),
+	}
+	if ok {
+		output.Item = project(item, projection)
+	}
+	return output, nil
+}
@@ -0,0 +1,438 @@
+// Package goddbtest provides an in-memory DynamoDB backend for testing code
+// built on goddb without a real table.
+//
+//	store := goddbtest.New()
+//	db, _ := goddb.New(goddb.WithTableName("test"), goddb.WithClient(store))
+//
+// The Store understands the requests goddb issues: key condition queries,
+// scans, condition, filter, update and projection expressions, global
+// secondary indexes, batch reads and writes and transactions. Tables are
+// created on first use.
+// Global secondary indexes are inferred from their names: an index named
+// <Struct>GSI has the simple key <Struct>GSI, any other index <Name> has the
+// composite key <Name>PK and <Name>SK. Other key schemas can be declared
+// with WithIndex. Every index projects all attributes.
+package goddbtest
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxItemSize = 400 * 1024
+
+const maxPageBytes = 1024 * 1024
+
+type Store struct {
+	mu        sync.Mutex
+	pkName    string
+	skName    string
+	indexes   map[string]index
+	pageSize  int
+	batchSize int
+	tables    map[string]*table
+	tokens    map[string]idempotentTransaction
+}
+
+type index struct {
+	hash  string
+	rng   string
+	table bool
+}
+
+type table struct {
+	name  string
+	items map[string]map[string]types.AttributeValue
+}
+
+type Option func(*Store)
+
+// WithKeyAttributes sets the names of the partition and sort key attributes
+// of every table. Defaults to PK and SK.
+func WithKeyAttributes(pk string, sk string) Option {
+	return func(s *Store) {
+		s.pkName = pk
+		s.skName = sk
+	}
+}
+
+// WithIndex declares the key schema of a global secondary index. sk may be
+// empty for a simple key.
+func WithIndex(name string, pk string, sk string) Option {
+	return func(s *Store) {
+		s.indexes[name] = index{hash: pk, rng: sk}
+	}
+}
+
+// WithPageSize limits the number of items evaluated by a single Query or
+// Scan call, which makes pagination testable with few items. DynamoDB's
+// 1 MB page limit is always applied.
+func WithPageSize(n int) Option {
+	return func(s *Store) {
+		s.pageSize = n
+	}
+}
+
+// WithBatchSize limits the number of keys or items processed by a single
+// batch call. The rest are returned as unprocessed, which makes retries of
+// unprocessed keys and items testable.
+func WithBatchSize(n int) Option {
+	return func(s *Store) {
+		s.batchSize = n
+	}
+}
+
+func New(opts ...Option) *Store {
+	s := &Store{
+		pkName:  "PK",
+		skName:  "SK",
+		indexes: make(map[string]index),
+		tables:  make(map[string]*table),
+		tokens:  make(map[string]idempotentTransaction),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Seed stores items in the table as is, replacing items with the same key.
+func (s *Store) Seed(tableName string, items ...map[string]types.AttributeValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.table(aws.String(tableName))
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := s.validateItem(item); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		t.items[s.key(item)] = copyItem(item)
+	}
+	return nil
+}
+
+// Items returns a copy of every item in the table ordered by primary key.
+func (s *Store) Items(tableName string) []map[string]types.AttributeValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tables[tableName]
+	if !ok {
+		return nil
+	}
+	items := s.sorted(t, s.primary())
+	for i := range items {
+		items[i] = copyItem(items[i])
+	}
+	return items
+}
+
+// Item returns a copy of the item with the given primary key, or nil.
+func (s *Store) Item(tableName string, key map[string]types.AttributeValue) map[string]types.AttributeValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tables[tableName]
+	if !ok {
+		return nil
+	}
+	return copyItem(t.items[s.key(key)])
+}
+
+// Reset removes all tables.
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables = make(map[string]*table)
+}
+
+func (s *Store) table(name *string) (*table, error) {
+	if name == nil || *name == "" {
+		return nil, validationError("1 validation error detected: value at 'tableName' failed to satisfy constraint: member must not be null")
+	}
+	t, ok := s.tables[*name]
+	if !ok {
+		t = &table{name: *name, items: make(map[string]map[string]types.AttributeValue)}
+		s.tables[*name] = t
+	}
+	return t, nil
+}
+
+func (s *Store) primary() index {
+	return index{hash: s.pkName, rng: s.skName, table: true}
+}
+
+func (s *Store) index(name *string) index {
+	if name == nil {
+		return s.primary()
+	}
+	if idx, ok := s.indexes[*name]; ok {
+		return idx
+	}
+	if strings.HasSuffix(*name, "GSI") {
+		return index{hash: *name}
+	}
+	return index{hash: *name + "PK", rng: *name + "SK"}
+}
+
+// keyAttrs returns the attributes identifying an item in the index, which
+// are the attributes of a LastEvaluatedKey.
+func (s *Store) keyAttrs(idx index) []string {
+	attrs := []string{idx.hash}
+	if idx.rng != "" {
+		attrs = append(attrs, idx.rng)
+	}
+	if !idx.table {
+		attrs = append(attrs, s.pkName, s.skName)
+	}
+	return attrs
+}
+
+func (s *Store) key(item map[string]types.AttributeValue) string {
+	return keyString(item, s.pkName, s.skName)
+}
+
+func (s *Store) validateKey(key map[string]types.AttributeValue) error {
+	if len(key) != 2 || !isKeyType(key[s.pkName]) || !isKeyType(key[s.skName]) {
+		return validationError("the provided key element does not match the schema")
+	}
+	return nil
+}
+
+func (s *Store) validateItem(item map[string]types.AttributeValue) error {
+	for _, attr := range []string{s.pkName, s.skName} {
+		if !isKeyType(item[attr]) {
+			return validationError("one or more parameter values were invalid: missing the key %s in the item", attr)
+		}
+	}
+	for name, v := range item {
+		switch v := v.(type) {
+		case *types.AttributeValueMemberSS:
+			if len(v.Value) == 0 {
+				return validationError("one or more parameter values were invalid: an string set may not be empty; attribute: %s", name)
+			}
+		case *types.AttributeValueMemberNS:
+			if len(v.Value) == 0 {
+				return validationError("one or more parameter values were invalid: an number set may not be empty; attribute: %s", name)
+			}
+		case *types.AttributeValueMemberBS:
+			if len(v.Value) == 0 {
+				return validationError("one or more parameter values were invalid: an binary set may not be empty; attribute: %s", name)
+			}
+		}
+	}
+	if itemSize(item) > maxItemSize {
+		return validationError("item size has exceeded the maximum allowed size")
+	}
+	return nil
+}
+
+// sorted returns the items of the index ordered by its key, then by the
+// table's key.
+func (s *Store) sorted(t *table, idx index) []map[string]types.AttributeValue {
+	var items []map[string]types.AttributeValue
+	for _, item := range t.items {
+		if _, ok := item[idx.hash]; !ok {
+			continue
+		}
+		if _, ok := item[idx.rng]; idx.rng != "" && !ok {
+			continue
+		}
+		items = append(items, item)
+	}
+	attrs := s.keyAttrs(idx)
+	slices.SortFunc(items, func(a, b map[string]types.AttributeValue) int {
+		return compareKeys(a, b, attrs)
+	})
+	return items
+}
+
+func compareKeys(a, b map[string]types.AttributeValue, attrs []string) int {
+	for _, attr := range attrs {
+		x, y := a[attr], b[attr]
+		if c := cmp.Compare(typeOf(x), typeOf(y)); c != 0 {
+			return c
+		}
+		if c, ok := compare(x, y); ok && c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// write is a prepared single item write shared by the item operations and
+// TransactWriteItems.
+type write struct {
+	table *table
+	key   string
+	cond  condition
+	// apply returns the new item, or nil if the item is deleted, and the
+	// names of updated attributes. It is nil for condition checks.
+	apply func(old map[string]types.AttributeValue) (map[string]types.AttributeValue, []string, error)
+}
+
+func (s *Store) prepareCondition(w *write, expr *string, ctx *exprContext) error {
+	if expr == nil {
+		return nil
+	}
+	cond, _, err := parseCondition(*expr, ctx)
+	if err != nil {
+		return validationError("invalid ConditionExpression: %s", err)
+	}
+	w.cond = cond
+	return nil
+}
+
+func (s *Store) preparePut(tableName *string, item map[string]types.AttributeValue, condExpr *string, names map[string]string, values map[string]types.AttributeValue) (*write, error) {
+	t, err := s.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateItem(item); err != nil {
+		return nil, err
+	}
+	w := &write{table: t, key: s.key(item)}
+	ctx := newExprContext(names, values)
+	if err := s.prepareCondition(w, condExpr, ctx); err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, validationError("%s", err)
+	}
+	item = copyItem(item)
+	w.apply = func(map[string]types.AttributeValue) (map[string]types.AttributeValue, []string, error) {
+		return item, nil, nil
+	}
+	return w, nil
+}
+
+func (s *Store) prepareDelete(tableName *string, key map[string]types.AttributeValue, condExpr *string, names map[string]string, values map[string]types.AttributeValue) (*write, error) {
+	t, err := s.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateKey(key); err != nil {
+		return nil, err
+	}
+	w := &write{table: t, key: s.key(key)}
+	ctx := newExprContext(names, values)
+	if err := s.prepareCondition(w, condExpr, ctx); err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, validationError("%s", err)
+	}
+	w.apply = func(map[string]types.AttributeValue) (map[string]types.AttributeValue, []string, error) {
+		return nil, nil, nil
+	}
+	return w, nil
+}
+
+func (s *Store) prepareUpdate(tableName *string, key map[string]types.AttributeValue, updateExpr *string, condExpr *string, names map[string]string, values map[string]types.AttributeValue) (*write, error) {
+	t, err := s.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateKey(key); err != nil {
+		return nil, err
+	}
+	w := &write{table: t, key: s.key(key)}
+	ctx := newExprContext(names, values)
+	var actions []updateAction
+	if updateExpr != nil {
+		actions, err = parseUpdate(*updateExpr, ctx)
+		if err != nil {
+			return nil, validationError("invalid UpdateExpression: %s", err)
+		}
+	}
+	if err := s.prepareCondition(w, condExpr, ctx); err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, validationError("%s", err)
+	}
+	key = copyItem(key)
+	w.apply = func(old map[string]types.AttributeValue) (map[string]types.AttributeValue, []string, error) {
+		if old == nil {
+			old = key
+		}
+		item, updated, err := applyUpdate(old, actions, []string{s.pkName, s.skName})
+		if err != nil {
+			return nil, nil, validationError("%s", err)
+		}
+		if updated == nil {
+			updated = []string{}
+		}
+		return item, updated, nil
+	}
+	return w, nil
+}
+
+func (s *Store) prepareConditionCheck(tableName *string, key map[string]types.AttributeValue, condExpr *string, names map[string]string, values map[string]types.AttributeValue) (*write, error) {
+	if condExpr == nil {
+		return nil, validationError("ConditionExpression is required for ConditionCheck")
+	}
+	w, err := s.prepareDelete(tableName, key, condExpr, names, values)
+	if err != nil {
+		return nil, err
+	}
+	w.apply = nil
+	return w, nil
+}
+
+// check returns a ConditionalCheckFailedException if the condition of w is
+// not met by the current item.
+func (s *Store) check(w *write, returnOnFailure types.ReturnValuesOnConditionCheckFailure) error {
+	if w.cond == nil {
+		return nil
+	}
+	old := w.table.items[w.key]
+	if w.cond.eval(old) {
+		return nil
+	}
+	ex := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	if returnOnFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+		ex.Item = copyItem(old)
+	}
+	return ex
+}
+
+// commit checks the condition of w and applies it. It returns the old and
+// new item and the updated attribute names.
+func (s *Store) commit(w *write, returnOnFailure types.ReturnValuesOnConditionCheckFailure) (map[string]types.AttributeValue, map[string]types.AttributeValue, []string, error) {
+	if err := s.check(w, returnOnFailure); err != nil {
+		return nil, nil, nil, err
+	}
+	old := w.table.items[w.key]
+	item, updated, err := s.applyWrite(w)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return old, item, updated, nil
+}
+
+func (s *Store) applyWrite(w *write) (map[string]types.AttributeValue, []string, error) {
+	if w.apply == nil {
+		return nil, nil, nil
+	}
+	item, updated, err := w.apply(w.table.items[w.key])
+	if err != nil {
+		return nil, nil, err
+	}
+	if item == nil {
+		delete(w.table.items, w.key)
+		return nil, updated, nil
+	}
+	if err := s.validateItem(item); err != nil {
+		return nil, nil, err
+	}
+	w.table.items[w.key] = item
+	return item, updated, nil
+}
@@ -0,0 +1,333 @@
+package goddbtest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/twharmon/goddb"
+	"github.com/twharmon/goddb/goddbtest"
+)
+
+var _ goddb.Client = (*goddbtest.Store)(nil)
+
+func s(v string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: v}
+}
+
+func n(v string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: v}
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+func TestSeedAndItems(t *testing.T) {
+	store := goddbtest.New()
+	assert.Equal(t, store.Seed("test",
+		map[string]types.AttributeValue{"PK": s("b"), "SK": s("b")},
+		map[string]types.AttributeValue{"PK": s("a"), "SK": s("a"), "Foo": n("1")},
+	), nil)
+	items := store.Items("test")
+	assert.Equal(t, len(items), 2)
+	assert.Equal(t, items[0]["PK"], s("a"))
+	assert.Equal(t, store.Item("test", map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")})["Foo"], n("1"))
+	assert.NotEqual(t, store.Seed("test", map[string]types.AttributeValue{"PK": s("a")}), nil)
+	store.Reset()
+	assert.Equal(t, len(store.Items("test")), 0)
+}
+
+func TestConditionExpression(t *testing.T) {
+	store := goddbtest.New()
+	ctx := context.Background()
+	item := map[string]types.AttributeValue{"PK": s("a"), "SK": s("a"), "Foo": n("5"), "Bar": s("bar")}
+	assert.Equal(t, store.Seed("test", item), nil)
+	tests := []struct {
+		expr   string
+		values map[string]types.AttributeValue
+		ok     bool
+	}{
+		{"#Foo = :0", map[string]types.AttributeValue{":0": n("5.0")}, true},
+		{"#Foo > :0 and #Bar <> :1", map[string]types.AttributeValue{":0": n("4"), ":1": s("baz")}, true},
+		{"(#Foo < :0) or (#Bar = :1)", map[string]types.AttributeValue{":0": n("4"), ":1": s("baz")}, false},
+		{"#Foo between :0 and :1", map[string]types.AttributeValue{":0": n("1"), ":1": n("10")}, true},
+		{"#Bar in (:0, :1)", map[string]types.AttributeValue{":0": s("foo"), ":1": s("bar")}, true},
+		{"begins_with(#Bar, :0) and not contains(#Bar, :1)", map[string]types.AttributeValue{":0": s("ba"), ":1": s("z")}, true},
+		{"attribute_exists(#Foo) and attribute_not_exists(#Baz)", nil, true},
+		{"size(#Bar) = :0 and attribute_type(#Foo, :1)", map[string]types.AttributeValue{":0": n("3"), ":1": s("N")}, true},
+	}
+	for _, test := range tests {
+		names := make(map[string]string)
+		for _, name := range []string{"Foo", "Bar", "Baz"} {
+			if strings.Contains(test.expr, "#"+name) {
+				names["#"+name] = name
+			}
+		}
+		_, err := store.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:                 aws.String("test"),
+			Item:                      item,
+			ConditionExpression:       aws.String(test.expr),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: test.values,
+		})
+		if test.ok {
+			assert.Equal(t, err, nil, test.expr)
+		} else {
+			var ex *types.ConditionalCheckFailedException
+			assert.True(t, errors.As(err, &ex), test.expr)
+		}
+	}
+}
+
+func TestExpressionValidation(t *testing.T) {
+	store := goddbtest.New()
+	ctx := context.Background()
+	_, err := store.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String("test"),
+		Item:                      map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")},
+		ConditionExpression:       aws.String("attribute_not_exists(#PK)"),
+		ExpressionAttributeNames:  map[string]string{"#PK": "PK"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":0": s("unused")},
+	})
+	assert.Equal(t, errorCode(err), "ValidationException")
+	_, err = store.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String("test"),
+		Item:                map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")},
+		ConditionExpression: aws.String("#Foo = "),
+	})
+	assert.Equal(t, errorCode(err), "ValidationException")
+	_, err = store.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("test"),
+		Key:       map[string]types.AttributeValue{"PK": s("a")},
+	})
+	assert.Equal(t, errorCode(err), "ValidationException")
+}
+
+func TestUpdateExpression(t *testing.T) {
+	store := goddbtest.New()
+	ctx := context.Background()
+	key := map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")}
+	assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{
+		"PK":   s("a"),
+		"SK":   s("a"),
+		"Num":  n("1"),
+		"Set":  &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"Gone": s("gone"),
+	}), nil)
+	output, err := store.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String("test"),
+		Key:              key,
+		UpdateExpression: aws.String("SET #Name = :0, #Total = #Num + :1 ADD #Num :1, #Set :2 DELETE #Other :3 REMOVE #Gone"),
+		ExpressionAttributeNames: map[string]string{
+			"#Name":  "Name",
+			"#Total": "Total",
+			"#Num":   "Num",
+			"#Set":   "Set",
+			"#Other": "Other",
+			"#Gone":  "Gone",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":0": s("foo"),
+			":1": n("2.5"),
+			":2": &types.AttributeValueMemberSS{Value: []string{"b", "c"}},
+			":3": &types.AttributeValueMemberSS{Value: []string{"x"}},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, output.Attributes["Num"], n("3.5"))
+	assert.Equal(t, output.Attributes["Total"], n("3.5"))
+	item := store.Item("test", key)
+	assert.Equal(t, item["Name"], s("foo"))
+	assert.Equal(t, item["Set"], &types.AttributeValueMemberSS{Value: []string{"a", "b", "c"}})
+	_, ok := item["Gone"]
+	assert.False(t, ok)
+	_, err = store.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("test"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET #PK = :0"),
+		ExpressionAttributeNames:  map[string]string{"#PK": "PK"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":0": s("b")},
+	})
+	assert.Equal(t, errorCode(err), "ValidationException")
+	_, err = store.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("test"),
+		Key:                       key,
+		UpdateExpression:          aws.String("DELETE #Set :0"),
+		ExpressionAttributeNames:  map[string]string{"#Set": "Set"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":0": &types.AttributeValueMemberSS{Value: []string{"a", "b", "c"}}},
+	})
+	assert.Equal(t, err, nil)
+	_, ok = store.Item("test", key)["Set"]
+	assert.False(t, ok)
+}
+
+func TestQueryIndex(t *testing.T) {
+	store := goddbtest.New(goddbtest.WithPageSize(2))
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{
+			"PK":     s("Author#x"),
+			"SK":     s("Post#" + id),
+			"GSI1PK": s("Category#foo"),
+			"GSI1SK": s("Post#" + id),
+		}), nil)
+	}
+	assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{"PK": s("Author#x"), "SK": s("Post#d")}), nil)
+	input := &dynamodb.QueryInput{
+		TableName:                aws.String("test"),
+		IndexName:                aws.String("GSI1"),
+		KeyConditionExpression:   aws.String("#pk = :pk and begins_with(#sk, :sk)"),
+		ExpressionAttributeNames: map[string]string{"#pk": "GSI1PK", "#sk": "GSI1SK"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": s("Category#foo"),
+			":sk": s("Post#"),
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+	output, err := store.Query(ctx, input)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(output.Items), 2)
+	assert.Equal(t, output.Items[0]["SK"], s("Post#c"))
+	assert.Equal(t, len(output.LastEvaluatedKey), 4)
+	input.ExclusiveStartKey = output.LastEvaluatedKey
+	output, err = store.Query(ctx, input)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(output.Items), 1)
+	assert.Equal(t, output.Items[0]["SK"], s("Post#a"))
+	assert.Equal(t, len(output.LastEvaluatedKey), 0)
+	input.ConsistentRead = aws.Bool(true)
+	_, err = store.Query(ctx, input)
+	assert.Equal(t, errorCode(err), "ValidationException")
+}
+
+func TestScanSegments(t *testing.T) {
+	store := goddbtest.New()
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{"PK": s(id), "SK": s(id), "UserGSI": s(id)}), nil)
+	}
+	var total int
+	for segment := int32(0); segment < 3; segment++ {
+		output, err := store.Scan(ctx, &dynamodb.ScanInput{
+			TableName:     aws.String("test"),
+			IndexName:     aws.String("UserGSI"),
+			Segment:       aws.Int32(segment),
+			TotalSegments: aws.Int32(3),
+		})
+		assert.Equal(t, err, nil)
+		total += len(output.Items)
+	}
+	assert.Equal(t, total, 6)
+}
+
+func TestTransactWriteItems(t *testing.T) {
+	store := goddbtest.New()
+	ctx := context.Background()
+	assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{"PK": s("stock"), "SK": s("stock"), "Count": n("0")}), nil)
+	_, err := store.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String("test"),
+				Item:      map[string]types.AttributeValue{"PK": s("order"), "SK": s("order")},
+			}},
+			{Update: &types.Update{
+				TableName:                 aws.String("test"),
+				Key:                       map[string]types.AttributeValue{"PK": s("stock"), "SK": s("stock")},
+				UpdateExpression:          aws.String("ADD #Count :0"),
+				ConditionExpression:       aws.String("#Count >= :1"),
+				ExpressionAttributeNames:  map[string]string{"#Count": "Count"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{":0": n("-1"), ":1": n("1")},
+			}},
+		},
+	})
+	var ex *types.TransactionCanceledException
+	assert.True(t, errors.As(err, &ex))
+	assert.Equal(t, *ex.CancellationReasons[0].Code, "None")
+	assert.Equal(t, *ex.CancellationReasons[1].Code, "ConditionalCheckFailed")
+	assert.Equal(t, len(store.Items("test")), 1)
+	_, err = store.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Delete: &types.Delete{TableName: aws.String("test"), Key: map[string]types.AttributeValue{"PK": s("stock"), "SK": s("stock")}}},
+			{Delete: &types.Delete{TableName: aws.String("test"), Key: map[string]types.AttributeValue{"PK": s("stock"), "SK": s("stock")}}},
+		},
+	})
+	assert.Equal(t, errorCode(err), "ValidationException")
+}
+
+func TestContextCanceled(t *testing.T) {
+	store := goddbtest.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := store.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("test")})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	store := goddbtest.New()
+	ctx := context.Background()
+	put, err := store.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String("test"),
+		Item:                   map[string]types.AttributeValue{"PK": s("a"), "SK": s("a"), "GSI1PK": s("x"), "GSI1SK": s("a")},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, aws.ToFloat64(put.ConsumedCapacity.WriteCapacityUnits), 1.0)
+	get, err := store.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String("test"),
+		Key:            map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")},
+		ConsistentRead: aws.Bool(true),
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, get.ConsumedCapacity, (*types.ConsumedCapacity)(nil))
+	query, err := store.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("test"),
+		IndexName:                 aws.String("GSI1"),
+		KeyConditionExpression:    aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("x")},
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, aws.ToFloat64(query.ConsumedCapacity.GlobalSecondaryIndexes["GSI1"].ReadCapacityUnits), 0.5)
+	assert.Equal(t, query.ConsumedCapacity.Table, (*types.Capacity)(nil))
+}
+
+func TestBatchGetItem(t *testing.T) {
+	store := goddbtest.New(goddbtest.WithBatchSize(1))
+	ctx := context.Background()
+	assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")}), nil)
+	keys := []map[string]types.AttributeValue{{"PK": s("a"), "SK": s("a")}, {"PK": s("b"), "SK": s("b")}}
+	output, err := store.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{"test": {Keys: keys}}})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(output.Responses["test"]), 1)
+	assert.Equal(t, output.UnprocessedKeys["test"].Keys, keys[1:])
+	_, err = store.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{"test": {Keys: append(keys, keys[0])}}})
+	assert.Equal(t, errorCode(err), "ValidationException")
+}
+
+func TestBatchWriteItem(t *testing.T) {
+	store := goddbtest.New(goddbtest.WithBatchSize(1))
+	ctx := context.Background()
+	assert.Equal(t, store.Seed("test", map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")}), nil)
+	reqs := []types.WriteRequest{
+		{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{"PK": s("a"), "SK": s("a")}}},
+		{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{"PK": s("b"), "SK": s("b")}}},
+	}
+	output, err := store.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{"test": reqs}})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, output.UnprocessedItems["test"], reqs[1:])
+	assert.Equal(t, len(store.Items("test")), 0)
+	_, err = store.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{"test": append(reqs, reqs[0])}})
+	assert.Equal(t, errorCode(err), "ValidationException")
+}
@@ -0,0 +1,36 @@
+package goddbtest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func (s *Store) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("PutItem", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, err := s.preparePut(params.TableName, params.Item, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, operationError("PutItem", err)
+	}
+	switch params.ReturnValues {
+	case "", types.ReturnValueNone, types.ReturnValueAllOld:
+	default:
+		return nil, operationError("PutItem", validationError("return values %s is not valid for PutItem", params.ReturnValues))
+	}
+	old, _, _, err := s.commit(w, params.ReturnValuesOnConditionCheckFailure)
+	if err != nil {
+		return nil, operationError("PutItem", err)
+	}
+	output := &dynamodb.PutItemOutput{
+		ConsumedCapacity: consumed(params.ReturnConsumedCapacity, params.TableName, "", 0, writeUnits(max(itemSize(old), itemSize(params.Item)))),
+	}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+	return output, nil
+}
@@ -0,0 +1,249 @@
+package goddbtest
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// read holds the parameters shared by Query and Scan.
+type read struct {
+	tableName     *string
+	indexName     *string
+	consistent    bool
+	keyCondition  *string
+	filter        *string
+	projection    *string
+	names         map[string]string
+	values        map[string]types.AttributeValue
+	selects       types.Select
+	limit         *int32
+	backward      bool
+	capacity      types.ReturnConsumedCapacity
+	startKey      map[string]types.AttributeValue
+	segment       *int32
+	totalSegments *int32
+}
+
+type readResult struct {
+	items        []map[string]types.AttributeValue
+	count        int
+	scannedCount int
+	lastKey      map[string]types.AttributeValue
+	capacity     *types.ConsumedCapacity
+}
+
+func (s *Store) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("Query", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if params.KeyConditionExpression == nil {
+		return nil, operationError("Query", validationError("either the KeyConditions or KeyConditionExpression parameter must be specified in the request"))
+	}
+	result, err := s.read(&read{
+		tableName:    params.TableName,
+		indexName:    params.IndexName,
+		consistent:   aws.ToBool(params.ConsistentRead),
+		keyCondition: params.KeyConditionExpression,
+		filter:       params.FilterExpression,
+		projection:   params.ProjectionExpression,
+		names:        params.ExpressionAttributeNames,
+		values:       params.ExpressionAttributeValues,
+		selects:      params.Select,
+		limit:        params.Limit,
+		backward:     params.ScanIndexForward != nil && !*params.ScanIndexForward,
+		startKey:     params.ExclusiveStartKey,
+		capacity:     params.ReturnConsumedCapacity,
+	})
+	if err != nil {
+		return nil, operationError("Query", err)
+	}
+	return &dynamodb.QueryOutput{
+		Items:            result.items,
+		Count:            int32(result.count),
+		ScannedCount:     int32(result.scannedCount),
+		LastEvaluatedKey: result.lastKey,
+		ConsumedCapacity: result.capacity,
+	}, nil
+}
+
+func (s *Store) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("Scan", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, err := s.read(&read{
+		tableName:     params.TableName,
+		indexName:     params.IndexName,
+		consistent:    aws.ToBool(params.ConsistentRead),
+		filter:        params.FilterExpression,
+		projection:    params.ProjectionExpression,
+		names:         params.ExpressionAttributeNames,
+		values:        params.ExpressionAttributeValues,
+		selects:       params.Select,
+		limit:         params.Limit,
+		startKey:      params.ExclusiveStartKey,
+		segment:       params.Segment,
+		totalSegments: params.TotalSegments,
+		capacity:      params.ReturnConsumedCapacity,
+	})
+	if err != nil {
+		return nil, operationError("Scan", err)
+	}
+	return &dynamodb.ScanOutput{
+		Items:            result.items,
+		Count:            int32(result.count),
+		ScannedCount:     int32(result.scannedCount),
+		LastEvaluatedKey: result.lastKey,
+		ConsumedCapacity: result.capacity,
+	}, nil
+}
+
+func (s *Store) read(r *read) (*readResult, error) {
+	t, err := s.table(r.tableName)
+	if err != nil {
+		return nil, err
+	}
+	idx := s.index(r.indexName)
+	if r.consistent && !idx.table {
+		return nil, validationError("consistent reads are not supported on global secondary indexes")
+	}
+	if r.limit != nil && *r.limit <= 0 {
+		return nil, validationError("1 validation error detected: value at 'limit' failed to satisfy constraint: member must have value greater than or equal to 1")
+	}
+	if (r.segment == nil) != (r.totalSegments == nil) {
+		return nil, validationError("the TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	}
+	if r.segment != nil && (*r.totalSegments < 1 || *r.segment < 0 || *r.segment >= *r.totalSegments) {
+		return nil, validationError("the Segment parameter must be less than the TotalSegments parameter")
+	}
+	ctx := newExprContext(r.names, r.values)
+	var keyCond condition
+	if r.keyCondition != nil {
+		var attrs []string
+		keyCond, attrs, err = parseCondition(*r.keyCondition, ctx)
+		if err != nil {
+			return nil, validationError("invalid KeyConditionExpression: %s", err)
+		}
+		if !slices.Contains(attrs, idx.hash) {
+			return nil, validationError("query condition missed key schema element: %s", idx.hash)
+		}
+		for _, attr := range attrs {
+			if attr != idx.hash && attr != idx.rng {
+				return nil, validationError("query key condition not supported; %s is not a key attribute", attr)
+			}
+		}
+	}
+	var filter condition
+	if r.filter != nil {
+		var attrs []string
+		filter, attrs, err = parseCondition(*r.filter, ctx)
+		if err != nil {
+			return nil, validationError("invalid FilterExpression: %s", err)
+		}
+		if r.keyCondition != nil {
+			for _, attr := range attrs {
+				if attr == idx.hash || attr == idx.rng {
+					return nil, validationError("filter expression can only contain non-primary key attributes: primary key attribute: %s", attr)
+				}
+			}
+		}
+	}
+	var projection []string
+	if r.projection != nil {
+		projection, err = parseProjection(*r.projection, ctx)
+		if err != nil {
+			return nil, validationError("invalid ProjectionExpression: %s", err)
+		}
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, validationError("%s", err)
+	}
+	switch r.selects {
+	case "", types.SelectAllAttributes, types.SelectAllProjectedAttributes, types.SelectCount:
+	case types.SelectSpecificAttributes:
+		if projection == nil {
+			return nil, validationError("ProjectionExpression is required when Select is SPECIFIC_ATTRIBUTES")
+		}
+	default:
+		return nil, validationError("invalid Select value %s", r.selects)
+	}
+	keyAttrs := s.keyAttrs(idx)
+	if r.startKey != nil {
+		if len(r.startKey) != len(keyAttrs) {
+			return nil, validationError("the provided starting key is invalid")
+		}
+		for _, attr := range keyAttrs {
+			if !isKeyType(r.startKey[attr]) {
+				return nil, validationError("the provided starting key is invalid")
+			}
+		}
+	}
+	items := s.sorted(t, idx)
+	if r.backward {
+		slices.Reverse(items)
+	}
+	result := &readResult{}
+	var bytes int
+	for i, item := range items {
+		if r.segment != nil && segmentOf(item[idx.hash], *r.totalSegments) != *r.segment {
+			continue
+		}
+		if keyCond != nil && !keyCond.eval(item) {
+			continue
+		}
+		if r.startKey != nil {
+			c := compareKeys(item, r.startKey, keyAttrs)
+			if r.backward && c >= 0 || !r.backward && c <= 0 {
+				continue
+			}
+		}
+		result.scannedCount++
+		bytes += itemSize(item)
+		if filter == nil || filter.eval(item) {
+			result.count++
+			if r.selects != types.SelectCount {
+				result.items = append(result.items, project(item, projection))
+			}
+		}
+		limited := r.limit != nil && result.scannedCount == int(*r.limit)
+		full := s.pageSize > 0 && result.scannedCount == s.pageSize || bytes >= maxPageBytes
+		if limited || full && s.more(items[i+1:], r, keyCond, idx) {
+			result.lastKey = project(item, keyAttrs)
+			break
+		}
+	}
+	var indexName string
+	if !idx.table {
+		indexName = aws.ToString(r.indexName)
+	}
+	result.capacity = consumed(r.capacity, r.tableName, indexName, readUnits(bytes, r.consistent), 0)
+	return result, nil
+}
+
+// more reports whether any of the remaining items would be evaluated.
+func (s *Store) more(items []map[string]types.AttributeValue, r *read, keyCond condition, idx index) bool {
+	for _, item := range items {
+		if r.segment != nil && segmentOf(item[idx.hash], *r.totalSegments) != *r.segment {
+			continue
+		}
+		if keyCond != nil && !keyCond.eval(item) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func segmentOf(v types.AttributeValue, totalSegments int32) int32 {
+	h := fnv.New32a()
+	h.Write([]byte(keyString(map[string]types.AttributeValue{"hash": v}, "hash")))
+	return int32(h.Sum32() % uint32(totalSegments))
+}
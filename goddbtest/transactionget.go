@@ -0,0 +1,74 @@
+package goddbtest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TransactGetItems reads all items at the same point in time, since the
+// Store applies writes one at a time.
+func (s *Store) TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("TransactGetItems", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(params.TransactItems) == 0 || len(params.TransactItems) > maxTransactionItems {
+		return nil, operationError("TransactGetItems", validationError("1 validation error detected: value at 'transactItems' failed to satisfy constraint: member must have length less than or equal to %d and greater than or equal to 1", maxTransactionItems))
+	}
+	output := &dynamodb.TransactGetItemsOutput{
+		Responses: make([]types.ItemResponse, len(params.TransactItems)),
+	}
+	seen := make(map[*table]map[string]bool)
+	units := make(map[*table]float64)
+	var tables []*table
+	for i, item := range params.TransactItems {
+		get := item.Get
+		if get == nil {
+			return nil, operationError("TransactGetItems", validationError("transaction item %d must specify Get", i))
+		}
+		t, err := s.table(get.TableName)
+		if err != nil {
+			return nil, operationError("TransactGetItems", err)
+		}
+		if err := s.validateKey(get.Key); err != nil {
+			return nil, operationError("TransactGetItems", err)
+		}
+		exprCtx := newExprContext(get.ExpressionAttributeNames, nil)
+		var projection []string
+		if get.ProjectionExpression != nil {
+			projection, err = parseProjection(*get.ProjectionExpression, exprCtx)
+			if err != nil {
+				return nil, operationError("TransactGetItems", validationError("invalid ProjectionExpression: %s", err))
+			}
+		}
+		if err := exprCtx.checkUnused(); err != nil {
+			return nil, operationError("TransactGetItems", validationError("%s", err))
+		}
+		key := s.key(get.Key)
+		if seen[t] == nil {
+			seen[t] = make(map[string]bool)
+		}
+		if seen[t][key] {
+			return nil, operationError("TransactGetItems", validationError("transaction request cannot include multiple operations on one item"))
+		}
+		seen[t][key] = true
+		if _, ok := units[t]; !ok {
+			tables = append(tables, t)
+		}
+		// Transactional reads cost twice as much as consistent reads.
+		units[t] += 2 * readUnits(itemSize(t.items[key]), true)
+		if found, ok := t.items[key]; ok {
+			output.Responses[i].Item = project(found, projection)
+		}
+	}
+	for _, t := range tables {
+		if c := consumed(params.ReturnConsumedCapacity, aws.String(t.name), "", units[t], 0); c != nil {
+			output.ConsumedCapacity = append(output.ConsumedCapacity, *c)
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,168 @@
+package goddbtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxTransactionItems = 100
+
+// tokenTTL is how long a ClientRequestToken makes a transaction idempotent.
+const tokenTTL = 10 * time.Minute
+
+// idempotentTransaction is a transaction that succeeded with a ClientRequestToken.
+type idempotentTransaction struct {
+	items []types.TransactWriteItem
+	at    time.Time
+}
+
+// TransactWriteItems checks the conditions of all items before applying any
+// of them, so either every write is applied or none is.
+func (s *Store) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("TransactWriteItems", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requestToken := aws.ToString(params.ClientRequestToken)
+	if params.ClientRequestToken != nil && (len(requestToken) < 1 || len(requestToken) > 36) {
+		return nil, operationError("TransactWriteItems", validationError("1 validation error detected: value at 'clientRequestToken' failed to satisfy constraint: member must have length less than or equal to 36 and greater than or equal to 1"))
+	}
+	if t, ok := s.tokens[requestToken]; ok && time.Since(t.at) < tokenTTL {
+		if !reflect.DeepEqual(t.items, params.TransactItems) {
+			return nil, operationError("TransactWriteItems", &types.IdempotentParameterMismatchException{
+				Message: aws.String("the request uses the same client token as a previous, but non-identical request"),
+			})
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+	capacity, err := s.transactWrite(params)
+	if err != nil {
+		return nil, operationError("TransactWriteItems", err)
+	}
+	if requestToken != "" {
+		s.tokens[requestToken] = idempotentTransaction{items: params.TransactItems, at: time.Now()}
+	}
+	return &dynamodb.TransactWriteItemsOutput{ConsumedCapacity: capacity}, nil
+}
+
+func (s *Store) transactWrite(params *dynamodb.TransactWriteItemsInput) ([]types.ConsumedCapacity, error) {
+	if len(params.TransactItems) == 0 || len(params.TransactItems) > maxTransactionItems {
+		return nil, validationError("1 validation error detected: value at 'transactItems' failed to satisfy constraint: member must have length less than or equal to %d and greater than or equal to 1", maxTransactionItems)
+	}
+	writes := make([]*write, len(params.TransactItems))
+	returnOnFailure := make([]types.ReturnValuesOnConditionCheckFailure, len(params.TransactItems))
+	seen := make(map[*table]map[string]bool)
+	for i, item := range params.TransactItems {
+		var w *write
+		var err error
+		var ops int
+		if p := item.Put; p != nil {
+			ops++
+			w, err = s.preparePut(p.TableName, p.Item, p.ConditionExpression, p.ExpressionAttributeNames, p.ExpressionAttributeValues)
+			returnOnFailure[i] = p.ReturnValuesOnConditionCheckFailure
+		}
+		if d := item.Delete; d != nil {
+			ops++
+			w, err = s.prepareDelete(d.TableName, d.Key, d.ConditionExpression, d.ExpressionAttributeNames, d.ExpressionAttributeValues)
+			returnOnFailure[i] = d.ReturnValuesOnConditionCheckFailure
+		}
+		if u := item.Update; u != nil {
+			ops++
+			w, err = s.prepareUpdate(u.TableName, u.Key, u.UpdateExpression, u.ConditionExpression, u.ExpressionAttributeNames, u.ExpressionAttributeValues)
+			returnOnFailure[i] = u.ReturnValuesOnConditionCheckFailure
+		}
+		if c := item.ConditionCheck; c != nil {
+			ops++
+			w, err = s.prepareConditionCheck(c.TableName, c.Key, c.ConditionExpression, c.ExpressionAttributeNames, c.ExpressionAttributeValues)
+			returnOnFailure[i] = c.ReturnValuesOnConditionCheckFailure
+		}
+		if ops != 1 {
+			return nil, validationError("transaction item %d must specify exactly one of Put, Update, Delete or ConditionCheck", i)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[w.table] == nil {
+			seen[w.table] = make(map[string]bool)
+		}
+		if seen[w.table][w.key] {
+			return nil, validationError("transaction request cannot include multiple operations on one item")
+		}
+		seen[w.table][w.key] = true
+		writes[i] = w
+	}
+	reasons := make([]types.CancellationReason, len(writes))
+	items := make([]map[string]types.AttributeValue, len(writes))
+	var canceled bool
+	for i, w := range writes {
+		reasons[i].Code = aws.String("None")
+		if err := s.check(w, returnOnFailure[i]); err != nil {
+			ex := err.(*types.ConditionalCheckFailedException)
+			reasons[i] = types.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: ex.Message,
+				Item:    ex.Item,
+			}
+			canceled = true
+			continue
+		}
+		if w.apply == nil {
+			continue
+		}
+		item, _, err := w.apply(w.table.items[w.key])
+		if err == nil && item != nil {
+			err = s.validateItem(item)
+		}
+		if err != nil {
+			reasons[i] = types.CancellationReason{
+				Code:    aws.String("ValidationError"),
+				Message: aws.String(err.Error()),
+			}
+			canceled = true
+			continue
+		}
+		items[i] = item
+	}
+	if canceled {
+		codes := make([]string, len(reasons))
+		for i := range reasons {
+			codes[i] = *reasons[i].Code
+		}
+		return nil, &types.TransactionCanceledException{
+			Message:             aws.String(fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]", strings.Join(codes, ", "))),
+			CancellationReasons: reasons,
+		}
+	}
+	// Transactional writes cost twice as much as standard writes.
+	units := make(map[*table]float64)
+	var tables []*table
+	for i, w := range writes {
+		if _, ok := units[w.table]; !ok {
+			tables = append(tables, w.table)
+		}
+		units[w.table] += 2 * writeUnits(max(itemSize(w.table.items[w.key]), itemSize(items[i])))
+		if w.apply == nil {
+			continue
+		}
+		if items[i] == nil {
+			delete(w.table.items, w.key)
+			continue
+		}
+		w.table.items[w.key] = items[i]
+	}
+	var capacity []types.ConsumedCapacity
+	for _, t := range tables {
+		if c := consumed(params.ReturnConsumedCapacity, aws.String(t.name), "", 0, units[t]); c != nil {
+			capacity = append(capacity, *c)
+		}
+	}
+	return capacity, nil
+}
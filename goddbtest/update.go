@@ -0,0 +1,425 @@
+package goddbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type updateAction struct {
+	clause string
+	attr   string
+	value  setValue
+}
+
+type setValue interface {
+	eval(item map[string]types.AttributeValue) (types.AttributeValue, error)
+}
+
+var errMissingAttribute = errors.New("the provided expression refers to an attribute that does not exist in the item")
+
+type operandValue struct {
+	operand operand
+}
+
+func (v operandValue) eval(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	av, ok := v.operand.resolve(item)
+	if !ok {
+		return nil, errMissingAttribute
+	}
+	return av, nil
+}
+
+type arithmeticValue struct {
+	op          string
+	left, right setValue
+}
+
+func (v arithmeticValue) eval(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	l, err := v.left.eval(item)
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.right.eval(item)
+	if err != nil {
+		return nil, err
+	}
+	ln, lok := l.(*types.AttributeValueMemberN)
+	rn, rok := r.(*types.AttributeValueMemberN)
+	if !lok || !rok {
+		return nil, fmt.Errorf("an operand in the update expression has an incorrect data type")
+	}
+	x, ok := parseNumber(ln.Value)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", ln.Value)
+	}
+	y, ok := parseNumber(rn.Value)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", rn.Value)
+	}
+	if v.op == "-" {
+		y.Neg(y)
+	}
+	return &types.AttributeValueMemberN{Value: formatNumber(x.Add(x, y))}, nil
+}
+
+type ifNotExistsValue struct {
+	path     string
+	fallback setValue
+}
+
+func (v ifNotExistsValue) eval(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	if av, ok := item[v.path]; ok {
+		return av, nil
+	}
+	return v.fallback.eval(item)
+}
+
+type listAppendValue struct {
+	left, right setValue
+}
+
+func (v listAppendValue) eval(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	l, err := v.left.eval(item)
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.right.eval(item)
+	if err != nil {
+		return nil, err
+	}
+	ll, lok := l.(*types.AttributeValueMemberL)
+	rl, rok := r.(*types.AttributeValueMemberL)
+	if !lok || !rok {
+		return nil, fmt.Errorf("incorrect operand type for operator or function; operator or function: list_append")
+	}
+	return &types.AttributeValueMemberL{Value: append(slices.Clone(ll.Value), rl.Value...)}, nil
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := r.FloatString(38)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func parseUpdate(expr string, ctx *exprContext) ([]updateAction, error) {
+	p, err := newParser(expr, ctx)
+	if err != nil {
+		return nil, err
+	}
+	var actions []updateAction
+	seen := make(map[string]bool)
+	for p.peek().kind != tokenEOF {
+		t := p.next()
+		clause := strings.ToUpper(t.text)
+		if t.kind != tokenIdent || clause != "SET" && clause != "REMOVE" && clause != "ADD" && clause != "DELETE" {
+			p.pos--
+			return nil, p.syntaxError()
+		}
+		if seen[clause] {
+			return nil, fmt.Errorf("invalid update expression: the %q section can only be used once in an update expression", clause)
+		}
+		seen[clause] = true
+		for {
+			action, err := p.updateAction(clause)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, action)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("invalid update expression: the expression can not be empty")
+	}
+	attrs := make(map[string]bool)
+	for _, a := range actions {
+		if attrs[a.attr] {
+			return nil, fmt.Errorf("invalid update expression: two document paths overlap with each other; path: [%s]", a.attr)
+		}
+		attrs[a.attr] = true
+	}
+	return actions, nil
+}
+
+func (p *parser) updateAction(clause string) (updateAction, error) {
+	attr, err := p.path()
+	if err != nil {
+		return updateAction{}, err
+	}
+	action := updateAction{clause: clause, attr: attr}
+	switch clause {
+	case "SET":
+		if err := p.expect("="); err != nil {
+			return updateAction{}, err
+		}
+		left, err := p.setTerm()
+		if err != nil {
+			return updateAction{}, err
+		}
+		action.value = left
+		for _, op := range []string{"+", "-"} {
+			if p.accept(op) {
+				right, err := p.setTerm()
+				if err != nil {
+					return updateAction{}, err
+				}
+				action.value = arithmeticValue{op: op, left: left, right: right}
+				break
+			}
+		}
+	case "ADD", "DELETE":
+		t := p.next()
+		if t.kind != tokenValue {
+			p.pos--
+			return updateAction{}, p.syntaxError()
+		}
+		v, err := p.ctx.value(t.text)
+		if err != nil {
+			return updateAction{}, err
+		}
+		action.value = operandValue{operand: valueOperand{value: v}}
+	}
+	return action, nil
+}
+
+func (p *parser) setTerm() (setValue, error) {
+	if p.function("if_not_exists") {
+		attr, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		fallback, err := p.setTerm()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return ifNotExistsValue{path: attr, fallback: fallback}, nil
+	}
+	if p.function("list_append") {
+		left, err := p.setTerm()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		right, err := p.setTerm()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return listAppendValue{left: left, right: right}, nil
+	}
+	o, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return operandValue{operand: o}, nil
+}
+
+// applyUpdate evaluates all actions against item and returns the updated
+// copy along with the names of the attributes that were updated.
+func applyUpdate(item map[string]types.AttributeValue, actions []updateAction, keyAttrs []string) (map[string]types.AttributeValue, []string, error) {
+	out := copyItem(item)
+	var updated []string
+	for _, a := range actions {
+		if slices.Contains(keyAttrs, a.attr) {
+			return nil, nil, fmt.Errorf("cannot update attribute %s. This attribute is part of the key", a.attr)
+		}
+		updated = append(updated, a.attr)
+		if a.clause == "REMOVE" {
+			delete(out, a.attr)
+			continue
+		}
+		v, err := a.value.eval(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch a.clause {
+		case "SET":
+			out[a.attr] = copyValue(v)
+		case "ADD":
+			nv, err := add(item[a.attr], v)
+			if err != nil {
+				return nil, nil, err
+			}
+			out[a.attr] = nv
+		case "DELETE":
+			nv, err := remove(item[a.attr], v)
+			if err != nil {
+				return nil, nil, err
+			}
+			if nv == nil {
+				delete(out, a.attr)
+			} else {
+				out[a.attr] = nv
+			}
+		}
+	}
+	return out, updated, nil
+}
+
+func add(current, v types.AttributeValue) (types.AttributeValue, error) {
+	incorrect := fmt.Errorf("incorrect operand type for operator or function; operator: ADD, operand type: %s", typeOf(v))
+	switch v := v.(type) {
+	case *types.AttributeValueMemberN:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		c, ok := current.(*types.AttributeValueMemberN)
+		if !ok {
+			return nil, incorrect
+		}
+		return arithmeticValue{
+			op:    "+",
+			left:  operandValue{operand: valueOperand{value: c}},
+			right: operandValue{operand: valueOperand{value: v}},
+		}.eval(nil)
+	case *types.AttributeValueMemberSS:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		c, ok := current.(*types.AttributeValueMemberSS)
+		if !ok {
+			return nil, incorrect
+		}
+		return &types.AttributeValueMemberSS{Value: union(c.Value, v.Value, func(a, b string) bool { return a == b })}, nil
+	case *types.AttributeValueMemberNS:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		c, ok := current.(*types.AttributeValueMemberNS)
+		if !ok {
+			return nil, incorrect
+		}
+		return &types.AttributeValueMemberNS{Value: union(c.Value, v.Value, numbersEqual)}, nil
+	case *types.AttributeValueMemberBS:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		c, ok := current.(*types.AttributeValueMemberBS)
+		if !ok {
+			return nil, incorrect
+		}
+		return &types.AttributeValueMemberBS{Value: union(c.Value, v.Value, func(a, b []byte) bool { return string(a) == string(b) })}, nil
+	}
+	return nil, incorrect
+}
+
+func remove(current, v types.AttributeValue) (types.AttributeValue, error) {
+	incorrect := fmt.Errorf("incorrect operand type for operator or function; operator: DELETE, operand type: %s", typeOf(v))
+	if current == nil {
+		switch v.(type) {
+		case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+			return nil, nil
+		}
+		return nil, incorrect
+	}
+	switch v := v.(type) {
+	case *types.AttributeValueMemberSS:
+		c, ok := current.(*types.AttributeValueMemberSS)
+		if !ok {
+			return nil, incorrect
+		}
+		if rest := difference(c.Value, v.Value, func(a, b string) bool { return a == b }); len(rest) > 0 {
+			return &types.AttributeValueMemberSS{Value: rest}, nil
+		}
+		return nil, nil
+	case *types.AttributeValueMemberNS:
+		c, ok := current.(*types.AttributeValueMemberNS)
+		if !ok {
+			return nil, incorrect
+		}
+		if rest := difference(c.Value, v.Value, numbersEqual); len(rest) > 0 {
+			return &types.AttributeValueMemberNS{Value: rest}, nil
+		}
+		return nil, nil
+	case *types.AttributeValueMemberBS:
+		c, ok := current.(*types.AttributeValueMemberBS)
+		if !ok {
+			return nil, incorrect
+		}
+		if rest := difference(c.Value, v.Value, func(a, b []byte) bool { return string(a) == string(b) }); len(rest) > 0 {
+			return &types.AttributeValueMemberBS{Value: rest}, nil
+		}
+		return nil, nil
+	}
+	return nil, incorrect
+}
+
+func union[E any](a, b []E, eq func(E, E) bool) []E {
+	out := slices.Clone(a)
+	for _, x := range b {
+		if !slices.ContainsFunc(out, func(y E) bool { return eq(x, y) }) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func difference[E any](a, b []E, eq func(E, E) bool) []E {
+	var out []E
+	for _, x := range a {
+		if !slices.ContainsFunc(b, func(y E) bool { return eq(x, y) }) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func (s *Store) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled("UpdateItem", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, err := s.prepareUpdate(params.TableName, params.Key, params.UpdateExpression, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+	switch params.ReturnValues {
+	case "", types.ReturnValueNone, types.ReturnValueAllOld, types.ReturnValueAllNew, types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+	default:
+		return nil, operationError("UpdateItem", validationError("return values %s is not valid for UpdateItem", params.ReturnValues))
+	}
+	old, item, updated, err := s.commit(w, params.ReturnValuesOnConditionCheckFailure)
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+	output := &dynamodb.UpdateItemOutput{
+		ConsumedCapacity: consumed(params.ReturnConsumedCapacity, params.TableName, "", 0, writeUnits(max(itemSize(old), itemSize(item)))),
+	}
+	switch params.ReturnValues {
+	case types.ReturnValueAllOld:
+		output.Attributes = copyItem(old)
+	case types.ReturnValueAllNew:
+		output.Attributes = copyItem(item)
+	case types.ReturnValueUpdatedOld:
+		output.Attributes = project(old, updated)
+	case types.ReturnValueUpdatedNew:
+		output.Attributes = project(item, updated)
+	}
+	if len(output.Attributes) == 0 {
+		output.Attributes = nil
+	}
+	return output, nil
+}
@@ -0,0 +1,282 @@
+package goddbtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	out := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v types.AttributeValue) types.AttributeValue {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: bytes.Clone(v.Value)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: slices.Clone(v.Value)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: slices.Clone(v.Value)}
+	case *types.AttributeValueMemberBS:
+		bs := make([][]byte, len(v.Value))
+		for i := range v.Value {
+			bs[i] = bytes.Clone(v.Value[i])
+		}
+		return &types.AttributeValueMemberBS{Value: bs}
+	case *types.AttributeValueMemberL:
+		l := make([]types.AttributeValue, len(v.Value))
+		for i := range v.Value {
+			l[i] = copyValue(v.Value[i])
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(v.Value)}
+	}
+	return v
+}
+
+func typeOf(v types.AttributeValue) string {
+	switch v.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+	return ""
+}
+
+func parseNumber(s string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(s)
+}
+
+// compare orders two scalar values of the same type. ok is false if the
+// values are not comparable.
+func compare(a, b types.AttributeValue) (int, bool) {
+	switch a := a.(type) {
+	case *types.AttributeValueMemberS:
+		b, ok := b.(*types.AttributeValueMemberS)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a.Value, b.Value), true
+	case *types.AttributeValueMemberN:
+		b, ok := b.(*types.AttributeValueMemberN)
+		if !ok {
+			return 0, false
+		}
+		x, ok := parseNumber(a.Value)
+		if !ok {
+			return 0, false
+		}
+		y, ok := parseNumber(b.Value)
+		if !ok {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case *types.AttributeValueMemberB:
+		b, ok := b.(*types.AttributeValueMemberB)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(a.Value, b.Value), true
+	}
+	return 0, false
+}
+
+func equal(a, b types.AttributeValue) bool {
+	if typeOf(a) != typeOf(b) {
+		return false
+	}
+	switch a := a.(type) {
+	case *types.AttributeValueMemberS, *types.AttributeValueMemberN, *types.AttributeValueMemberB:
+		c, ok := compare(a, b)
+		return ok && c == 0
+	case *types.AttributeValueMemberBOOL:
+		return a.Value == b.(*types.AttributeValueMemberBOOL).Value
+	case *types.AttributeValueMemberNULL:
+		return true
+	case *types.AttributeValueMemberSS:
+		return sameSet(a.Value, b.(*types.AttributeValueMemberSS).Value, func(x, y string) bool { return x == y })
+	case *types.AttributeValueMemberNS:
+		return sameSet(a.Value, b.(*types.AttributeValueMemberNS).Value, numbersEqual)
+	case *types.AttributeValueMemberBS:
+		return sameSet(a.Value, b.(*types.AttributeValueMemberBS).Value, bytes.Equal)
+	case *types.AttributeValueMemberL:
+		bl := b.(*types.AttributeValueMemberL).Value
+		if len(a.Value) != len(bl) {
+			return false
+		}
+		for i := range a.Value {
+			if !equal(a.Value[i], bl[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberM:
+		bm := b.(*types.AttributeValueMemberM).Value
+		if len(a.Value) != len(bm) {
+			return false
+		}
+		for k, v := range a.Value {
+			w, ok := bm[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func numbersEqual(a, b string) bool {
+	x, ok := parseNumber(a)
+	if !ok {
+		return false
+	}
+	y, ok := parseNumber(b)
+	if !ok {
+		return false
+	}
+	return x.Cmp(y) == 0
+}
+
+func sameSet[E any](a, b []E, eq func(E, E) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		if !slices.ContainsFunc(b, func(y E) bool { return eq(x, y) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// size approximates the number of bytes DynamoDB counts for a value.
+func size(v types.AttributeValue) int {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return (len(v.Value)+1)/2 + 1
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberSS:
+		var n int
+		for _, s := range v.Value {
+			n += len(s)
+		}
+		return n
+	case *types.AttributeValueMemberNS:
+		var n int
+		for _, s := range v.Value {
+			n += (len(s)+1)/2 + 1
+		}
+		return n
+	case *types.AttributeValueMemberBS:
+		var n int
+		for _, b := range v.Value {
+			n += len(b)
+		}
+		return n
+	case *types.AttributeValueMemberL:
+		n := 3
+		for _, e := range v.Value {
+			n += size(e) + 1
+		}
+		return n
+	case *types.AttributeValueMemberM:
+		return 3 + itemSize(v.Value)
+	}
+	return 0
+}
+
+func itemSize(item map[string]types.AttributeValue) int {
+	var n int
+	for k, v := range item {
+		n += len(k) + size(v)
+	}
+	return n
+}
+
+// keyString encodes the given attributes of an item so that equal keys
+// produce equal strings.
+func keyString(item map[string]types.AttributeValue, attrs ...string) string {
+	var b strings.Builder
+	for _, attr := range attrs {
+		if attr == "" {
+			continue
+		}
+		b.WriteString(attr)
+		b.WriteByte(0)
+		switch v := item[attr].(type) {
+		case *types.AttributeValueMemberS:
+			b.WriteString("S")
+			b.WriteString(v.Value)
+		case *types.AttributeValueMemberN:
+			r, ok := parseNumber(v.Value)
+			b.WriteString("N")
+			if ok {
+				b.WriteString(r.RatString())
+			} else {
+				b.WriteString(v.Value)
+			}
+		case *types.AttributeValueMemberB:
+			b.WriteString("B")
+			b.WriteString(base64.StdEncoding.EncodeToString(v.Value))
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func isKeyType(v types.AttributeValue) bool {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value != ""
+	case *types.AttributeValueMemberN:
+		_, ok := parseNumber(v.Value)
+		return ok
+	case *types.AttributeValueMemberB:
+		return len(v.Value) > 0
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package goddb
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// WithLogger logs every call made to DynamoDB at debug level, including the
+// operation, index, expressions and duration of the call.
+func WithLogger(logger *slog.Logger) Option {
+	return func(db *DB) {
+		db.logger = logger
+	}
+}
+
+// WithRedaction replaces the attribute values in logs with their type.
+func WithRedaction() Option {
+	return func(db *DB) {
+		db.redact = true
+	}
+}
+
+// logging returns a Middleware that logs requests with the logger of db.
+func (db *DB) logging(next Handler) Handler {
+	return func(ctx context.Context, req *Request) (any, error) {
+		if !db.logger.Enabled(ctx, slog.LevelDebug) {
+			return next(ctx, req)
+		}
+		start := time.Now()
+		out, err := next(ctx, req)
+		attrs := append([]slog.Attr{slog.String("op", string(req.Operation))}, describe(req.Input, db.redact)...)
+		attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		db.logger.LogAttrs(ctx, slog.LevelDebug, "goddb request", attrs...)
+		return out, err
+	}
+}
+
+// describe returns the parts of an input worth logging, starting with the
+// name of the call.
+func describe(input any, redact bool) []slog.Attr {
+	var call string
+	var table, index, keyCond, filter, cond, update, projection *string
+	var key, item, startKey map[string]types.AttributeValue
+	var names map[string]string
+	var values map[string]types.AttributeValue
+	var limit *int32
+	var consistent *bool
+	var desc bool
+	switch input := input.(type) {
+	case *dynamodb.GetItemInput:
+		call, table, key, projection, consistent = "GetItem", input.TableName, input.Key, input.ProjectionExpression, input.ConsistentRead
+		names = input.ExpressionAttributeNames
+	case *dynamodb.PutItemInput:
+		call, table, item, cond = "PutItem", input.TableName, input.Item, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	case *dynamodb.UpdateItemInput:
+		call, table, key, update, cond = "UpdateItem", input.TableName, input.Key, input.UpdateExpression, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	case *dynamodb.DeleteItemInput:
+		call, table, key, cond = "DeleteItem", input.TableName, input.Key, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	case *dynamodb.QueryInput:
+		call, table, index, keyCond, filter, projection = "Query", input.TableName, input.IndexName, input.KeyConditionExpression, input.FilterExpression, input.ProjectionExpression
+		names, values, limit, consistent, startKey = input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.Limit, input.ConsistentRead, input.ExclusiveStartKey
+		desc = input.ScanIndexForward != nil && !*input.ScanIndexForward
+	case *dynamodb.ScanInput:
+		call, table, index, filter, projection = "Scan", input.TableName, input.IndexName, input.FilterExpression, input.ProjectionExpression
+		names, values, limit, consistent, startKey = input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.Limit, input.ConsistentRead, input.ExclusiveStartKey
+	case *dynamodb.BatchGetItemInput:
+		attrs := []slog.Attr{slog.String("call", "BatchGetItem")}
+		for table, keys := range input.RequestItems {
+			attrs = append(attrs, slog.String("table", table), slog.Int("keys", len(keys.Keys)))
+		}
+		return attrs
+	case *dynamodb.BatchWriteItemInput:
+		attrs := []slog.Attr{slog.String("call", "BatchWriteItem")}
+		for table, reqs := range input.RequestItems {
+			attrs = append(attrs, slog.String("table", table), slog.Int("items", len(reqs)))
+		}
+		return attrs
+	case *dynamodb.TransactGetItemsInput:
+		return []slog.Attr{slog.String("call", "TransactGetItems"), slog.Int("items", len(input.TransactItems))}
+	case *dynamodb.TransactWriteItemsInput:
+		attrs := []slog.Attr{slog.String("call", "TransactWriteItems"), slog.Int("items", len(input.TransactItems))}
+		if input.ClientRequestToken != nil {
+			attrs = append(attrs, slog.String("token", *input.ClientRequestToken))
+		}
+		return attrs
+	case *types.Put:
+		call, table, item, cond = "Put", input.TableName, input.Item, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	case *types.Update:
+		call, table, key, update, cond = "Update", input.TableName, input.Key, input.UpdateExpression, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	case *types.Delete:
+		call, table, key, cond = "Delete", input.TableName, input.Key, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	case *types.Get:
+		call, table, key, projection, names = "Get", input.TableName, input.Key, input.ProjectionExpression, input.ExpressionAttributeNames
+	case *types.ConditionCheck:
+		call, table, key, cond = "ConditionCheck", input.TableName, input.Key, input.ConditionExpression
+		names, values = input.ExpressionAttributeNames, input.ExpressionAttributeValues
+	default:
+		return []slog.Attr{slog.String("call", fmt.Sprintf("%T", input))}
+	}
+	attrs := []slog.Attr{slog.String("call", call), slog.String("table", aws.ToString(table))}
+	for _, a := range []struct {
+		name string
+		expr *string
+	}{{"index", index}, {"key_condition", keyCond}, {"filter", filter}, {"condition", cond}, {"update", update}, {"projection", projection}} {
+		if a.expr != nil {
+			attrs = append(attrs, slog.String(a.name, *a.expr))
+		}
+	}
+	if len(names) > 0 {
+		attrs = append(attrs, slog.String("names", formatNames(names)))
+	}
+	for _, a := range []struct {
+		name string
+		item map[string]types.AttributeValue
+	}{{"key", key}, {"item", item}, {"values", values}, {"start_key", startKey}} {
+		if len(a.item) > 0 {
+			attrs = append(attrs, slog.String(a.name, formatItem(a.item, redact)))
+		}
+	}
+	if limit != nil {
+		attrs = append(attrs, slog.Int("limit", int(*limit)))
+	}
+	if aws.ToBool(consistent) {
+		attrs = append(attrs, slog.Bool("consistent", true))
+	}
+	if desc {
+		attrs = append(attrs, slog.Bool("desc", true))
+	}
+	return attrs
+}
+
+func formatNames(names map[string]string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range slices.Sorted(maps.Keys(names)) {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(names[k])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatItem(item map[string]types.AttributeValue, redact bool) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range slices.Sorted(maps.Keys(item)) {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteString(": ")
+		if redact {
+			b.WriteString(typeName(item[k]))
+		} else {
+			b.WriteString(formatValue(item[k]))
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func typeName(v types.AttributeValue) string {
+	switch v.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+	return "?"
+}
+
+// formatValue renders an attribute value in a form close to Go syntax.
+func formatValue(v types.AttributeValue) string {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return fmt.Sprintf("%q", v.Value)
+	case *types.AttributeValueMemberN:
+		return v.Value
+	case *types.AttributeValueMemberB:
+		return base64.StdEncoding.EncodeToString(v.Value)
+	case *types.AttributeValueMemberBOOL:
+		return fmt.Sprint(v.Value)
+	case *types.AttributeValueMemberNULL:
+		return "null"
+	case *types.AttributeValueMemberSS:
+		return fmt.Sprintf("%q", v.Value)
+	case *types.AttributeValueMemberNS:
+		return "[" + strings.Join(v.Value, " ") + "]"
+	case *types.AttributeValueMemberBS:
+		parts := make([]string, len(v.Value))
+		for i, b := range v.Value {
+			parts[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	case *types.AttributeValueMemberL:
+		parts := make([]string, len(v.Value))
+		for i, e := range v.Value {
+			parts[i] = formatValue(e)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	case *types.AttributeValueMemberM:
+		return formatItem(v.Value, false)
+	}
+	return "?"
+}
@@ -0,0 +1,109 @@
+package goddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+type Operation string
+
+const (
+	OperationGet              Operation = "Get"
+	OperationPut              Operation = "Put"
+	OperationUpdate           Operation = "Update"
+	OperationDelete           Operation = "Delete"
+	OperationQuery            Operation = "Query"
+	OperationDeleteAll        Operation = "DeleteAll"
+	OperationBatchGet         Operation = "BatchGet"
+	OperationBatchWrite       Operation = "BatchWrite"
+	OperationTransactionWrite Operation = "TransactionWrite"
+	OperationTransactionGet   Operation = "TransactionGet"
+)
+
+// Request is a single call to the DynamoDB API made while executing an
+// operation. A query that spans several pages makes one Request per page.
+type Request struct {
+	Operation Operation
+	// Value is the Go value the operation was built with.
+	Value any
+	// Input is the fully built input of the call, such as
+	// *dynamodb.GetItemInput for Get or *dynamodb.ScanInput for a Query
+	// that scans an index.
+	Input any
+}
+
+// Handler sends a request and returns its output, such as
+// *dynamodb.GetItemOutput for a *dynamodb.GetItemInput.
+type Handler func(ctx context.Context, req *Request) (any, error)
+
+// Middleware wraps a Handler. It may inspect or replace the request and its
+// output, or return without calling next.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds middleware to the DB. The first middleware given is
+// the outermost.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(db *DB) {
+		db.middleware = append(db.middleware, mw...)
+	}
+}
+
+func (db *DB) handler() Handler {
+	h := db.call
+	for i := len(db.middleware) - 1; i >= 0; i-- {
+		h = db.middleware[i](h)
+	}
+	return h
+}
+
+func (db *DB) call(ctx context.Context, req *Request) (any, error) {
+	switch input := req.Input.(type) {
+	case *dynamodb.GetItemInput:
+		return db.client.GetItem(ctx, input)
+	case *dynamodb.PutItemInput:
+		return db.client.PutItem(ctx, input)
+	case *dynamodb.UpdateItemInput:
+		return db.client.UpdateItem(ctx, input)
+	case *dynamodb.DeleteItemInput:
+		return db.client.DeleteItem(ctx, input)
+	case *dynamodb.QueryInput:
+		return db.client.Query(ctx, input)
+	case *dynamodb.ScanInput:
+		return db.client.Scan(ctx, input)
+	case *dynamodb.BatchGetItemInput:
+		return db.client.BatchGetItem(ctx, input)
+	case *dynamodb.BatchWriteItemInput:
+		return db.client.BatchWriteItem(ctx, input)
+	case *dynamodb.TransactGetItemsInput:
+		return db.client.TransactGetItems(ctx, input)
+	case *dynamodb.TransactWriteItemsInput:
+		return db.client.TransactWriteItems(ctx, input)
+	}
+	return nil, fmt.Errorf("unsupported input %T", req.Input)
+}
+
+// send runs the input through the middleware of db, retrying as allowed by
+// its retry policy, and returns the output.
+func send[O any](ctx context.Context, db *DB, op Operation, value any, input any) (*O, error) {
+	stats, _ := ctx.Value(statsKey{}).(*tracker)
+	if stats != nil {
+		requestCapacity(input)
+	}
+	out, err := db.retry.do(ctx, db, &Request{Operation: op, Value: value, Input: input})
+	if err != nil {
+		if stats != nil {
+			stats.record(nil)
+		}
+		return nil, classify(err)
+	}
+	if stats != nil {
+		stats.record(out)
+	}
+	output, ok := out.(*O)
+	if !ok {
+		return nil, fmt.Errorf("handler returned %T, expected %T", out, output)
+	}
+	return output, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -23,20 +24,16 @@ func offsetToLastEvaluatedKey(offset string) (map[string]types.AttributeValue, e
 	}
 	offset = string(offsetB)
 	lek := make(map[string]types.AttributeValue)
-	parts := strings.Split(offset, offsetPartSeparator)
-	pkParts := strings.Split(parts[0], offsetKeyValueSeparator)
-	pkValB, err := base64.URLEncoding.DecodeString(pkParts[1])
-	if err != nil {
-		return nil, err
-	}
-	lek[pkParts[0]] = &types.AttributeValueMemberS{Value: string(pkValB)}
-	if len(parts) > 1 {
-		skParts := strings.Split(parts[1], offsetKeyValueSeparator)
-		skValB, err := base64.URLEncoding.DecodeString(skParts[1])
+	for _, part := range strings.Split(offset, offsetPartSeparator) {
+		kv := strings.Split(part, offsetKeyValueSeparator)
+		if len(kv) != 2 {
+			return nil, errors.New("invalid offset")
+		}
+		valB, err := base64.URLEncoding.DecodeString(kv[1])
 		if err != nil {
 			return nil, err
 		}
-		lek[skParts[0]] = &types.AttributeValueMemberS{Value: string(skValB)}
+		lek[kv[0]] = &types.AttributeValueMemberS{Value: string(valB)}
 	}
 	return lek, nil
 }
@@ -54,3 +51,60 @@ func lastEvaluatedKeyToOffset(lek map[string]types.AttributeValue) (string, erro
 	raw := strings.Join(parts, offsetPartSeparator)
 	return base64.URLEncoding.EncodeToString([]byte(raw)), nil
 }
+
+// segmentDone is the offset of a segment of a parallel scan that has been
+// read to the end.
+var segmentDone = "~"
+
+// segmentsToLastEvaluatedKeys returns where each segment of a parallel scan
+// starts and whether it is done.
+func segmentsToLastEvaluatedKeys(offset string, segments int) ([]map[string]types.AttributeValue, []bool, error) {
+	leks := make([]map[string]types.AttributeValue, segments)
+	done := make([]bool, segments)
+	if offset == "" {
+		return leks, done, nil
+	}
+	offsetB, err := base64.URLEncoding.DecodeString(offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts := strings.Split(string(offsetB), offsetPartSeparator)
+	if len(parts) != segments {
+		return nil, nil, fmt.Errorf("offset has %d segments, expected %d", len(parts), segments)
+	}
+	for i, part := range parts {
+		if part == segmentDone {
+			done[i] = true
+			continue
+		}
+		if leks[i], err = offsetToLastEvaluatedKey(part); err != nil {
+			return nil, nil, err
+		}
+	}
+	return leks, done, nil
+}
+
+// lastEvaluatedKeysToSegments returns the offset of a parallel scan, which
+// is empty once every segment is done.
+func lastEvaluatedKeysToSegments(leks []map[string]types.AttributeValue, done []bool) (string, error) {
+	if !slices.Contains(done, false) {
+		return "", nil
+	}
+	parts := make([]string, len(leks))
+	for i, lek := range leks {
+		if done[i] {
+			parts[i] = segmentDone
+			continue
+		}
+		if lek == nil {
+			continue
+		}
+		part, err := lastEvaluatedKeyToOffset(lek)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	raw := strings.Join(parts, offsetPartSeparator)
+	return base64.URLEncoding.EncodeToString([]byte(raw)), nil
+}
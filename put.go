@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -13,17 +12,19 @@ import (
 )
 
 type PutRequest[T any] struct {
+	db        *DB
+	stats     *Stats
 	input     *dynamodb.PutItemInput
 	item      *T
 	condition *Condition[T]
+	returned  *T
+	onFailure *T
 }
 
 func Put[T any](item *T) *PutRequest[T] {
 	return &PutRequest[T]{
-		item: item,
-		input: &dynamodb.PutItemInput{
-			TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
-		},
+		item:  item,
+		input: &dynamodb.PutItemInput{},
 	}
 }
 
@@ -37,43 +38,130 @@ func (r *PutRequest[T]) If(condition *Condition[T]) *PutRequest[T] {
 	return r
 }
 
+// ReturnOld loads the item replaced by the put into v, if there was one.
+func (r *PutRequest[T]) ReturnOld(v *T) *PutRequest[T] {
+	r.input.ReturnValues = types.ReturnValueAllOld
+	r.returned = v
+	return r
+}
+
+// ReturnOldOnConditionFailure loads the existing item into v if the
+// condition fails.
+func (r *PutRequest[T]) ReturnOldOnConditionFailure(v *T) *PutRequest[T] {
+	r.input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	r.onFailure = v
+	return r
+}
+
+func (r *PutRequest[T]) In(db *DB) *PutRequest[T] {
+	r.db = db
+	return r
+}
+
+func (r *PutRequest[T]) Stats(s *Stats) *PutRequest[T] {
+	r.stats = s
+	return r
+}
+
 func (r *PutRequest[T]) Exec() error {
+	return r.ExecContext(context.Background())
+}
+
+func (r *PutRequest[T]) ExecContext(ctx context.Context) error {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb put: %w", err)
 	}
-	val, err := valueOf(r.item)
+	db, err := resolve(r.db)
 	if err != nil {
 		return wrap(err)
 	}
-	ty := val.Type()
-	r.input.Item, err = makeItem(ty, val, func(attr string) bool { return true })
+	ctx, done := db.track(ctx, OperationPut, r.stats)
+	defer done()
+	input, err := r.build(db)
+	if err != nil {
+		return wrap(invalid(err))
+	}
+	output, err := send[dynamodb.PutItemOutput](ctx, db, OperationPut, r.item, input)
 	if err != nil {
+		var ex *types.ConditionalCheckFailedException
+		if errors.As(err, &ex) {
+			if err := decode(db, r.onFailure, ex.Item); err != nil {
+				return wrap(err)
+			}
+		}
 		return wrap(err)
 	}
-	if err := validateCompleteKey(ty, val); err != nil {
+	if err := decode(db, r.returned, output.Attributes); err != nil {
 		return wrap(err)
 	}
+	return nil
+}
+
+func (r *PutRequest[T]) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb put: %w", err)
+	}
+	db, err := resolve(r.db)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	input, err := r.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: OperationPut, Input: input}, nil
+}
+
+func (r *PutRequest[T]) build(db *DB) (*dynamodb.PutItemInput, error) {
+	val, err := valueOf(r.item)
+	if err != nil {
+		return nil, err
+	}
+	ty := val.Type()
+	item, err := makeItem(ty, val, db.tagChar, func(attr string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCompleteKey(ty, val); err != nil {
+		return nil, err
+	}
+	input := *r.input
+	input.TableName = aws.String(db.tableName)
+	input.Item = db.toTable(item)
 	if r.condition != nil {
-		exp, names, values, err := r.condition.expression(len(r.input.ExpressionAttributeValues))
+		exp, names, values, err := r.condition.expression(len(input.ExpressionAttributeValues))
 		if err != nil {
-			return wrap(err)
-		}
-		r.input.ConditionExpression = &exp
-		r.input.ExpressionAttributeNames = merge(r.input.ExpressionAttributeNames, names)
-		r.input.ExpressionAttributeValues = merge(r.input.ExpressionAttributeValues, values)
-		fmt.Println(exp)
-		fmt.Println(r.input.ExpressionAttributeNames)
-		for k, v := range r.input.ExpressionAttributeValues {
-			fmt.Println(k, v)
+			return nil, err
 		}
+		input.ConditionExpression = &exp
+		input.ExpressionAttributeNames = merge(input.ExpressionAttributeNames, names)
+		input.ExpressionAttributeValues = merge(input.ExpressionAttributeValues, values)
 	}
-	_, err = client.PutItem(context.Background(), r.input)
+	return &input, nil
+}
+
+func (r *PutRequest[T]) transactionValue() any {
+	return r.item
+}
+
+func (r *PutRequest[T]) transactionItem(db *DB) (*types.TransactWriteItem, error) {
+	if err := sameDB(r.db, db); err != nil {
+		return nil, err
+	}
+	input, err := r.build(db)
 	if err != nil {
-		var ex *types.ConditionalCheckFailedException
-		if errors.As(err, &ex) {
-			return ErrConditionFailed
-		}
-		return wrap(err)
+		return nil, err
 	}
-	return nil
+	return &types.TransactWriteItem{Put: &types.Put{
+		Item:                                input.Item,
+		TableName:                           input.TableName,
+		ConditionExpression:                 input.ConditionExpression,
+		ExpressionAttributeNames:            input.ExpressionAttributeNames,
+		ExpressionAttributeValues:           input.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+	}}, nil
+}
+
+func (r *PutRequest[T]) transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error) {
+	return decodeOld(db, r.onFailure, attrs)
 }
@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"iter"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,6 +15,9 @@ import (
 )
 
 type QueryRequest[T any] struct {
+	db           *DB
+	stats        *Stats
+	operation    Operation
 	item         *T
 	limit        int
 	beginsWith   *T
@@ -21,11 +25,20 @@ type QueryRequest[T any] struct {
 	betweenEnd   *T
 	offset       *string
 	consistent   bool
+	selectors    []func(*T) any
+	filter       *Condition[T]
+	desc         bool
+	segments     int
+	after        *T
+	afterEqual   bool
+	before       *T
+	beforeEqual  bool
 }
 
 func Query[T any](item *T) *QueryRequest[T] {
 	return &QueryRequest[T]{
-		item: item,
+		item:      item,
+		operation: OperationQuery,
 	}
 }
 
@@ -40,204 +53,651 @@ func (r *QueryRequest[T]) Between(start *T, end *T) *QueryRequest[T] {
 	return r
 }
 
+// After returns the items with a sort key greater than that of v. It can be
+// combined with Before, but not with BeginsWith or Between. Items of other
+// types in the partition can match the key condition, so they may be read,
+// and consume capacity, before they are dropped.
+func (r *QueryRequest[T]) After(v *T) *QueryRequest[T] {
+	r.after, r.afterEqual = v, false
+	return r
+}
+
+// AfterOrEqual returns the items with a sort key greater than or equal to
+// that of v.
+func (r *QueryRequest[T]) AfterOrEqual(v *T) *QueryRequest[T] {
+	r.after, r.afterEqual = v, true
+	return r
+}
+
+// Before returns the items with a sort key less than that of v. It can be
+// combined with After, but not with BeginsWith or Between.
+func (r *QueryRequest[T]) Before(v *T) *QueryRequest[T] {
+	r.before, r.beforeEqual = v, false
+	return r
+}
+
+// BeforeOrEqual returns the items with a sort key less than or equal to that
+// of v.
+func (r *QueryRequest[T]) BeforeOrEqual(v *T) *QueryRequest[T] {
+	r.before, r.beforeEqual = v, true
+	return r
+}
+
+// Where returns only the items that meet the condition. Items are still
+// read, and consume capacity, before they are filtered.
+func (r *QueryRequest[T]) Where(condition *Condition[T]) *QueryRequest[T] {
+	r.filter = condition
+	return r
+}
+
+// Page returns at most maxSize items, starting after offset, and sets offset
+// to where the next page starts, or to an empty string after the last page.
 func (r *QueryRequest[T]) Page(maxSize int, offset *string) *QueryRequest[T] {
 	r.limit = maxSize
 	r.offset = offset
 	return r
 }
 
+// Desc returns the items in descending order of their sort key. Offsets of
+// pages continue in the same order when Desc is used for the next page too.
+// It is not supported on <Struct>GSI indexes that are scanned because their
+// fields are zero.
+func (r *QueryRequest[T]) Desc() *QueryRequest[T] {
+	r.desc = true
+	return r
+}
+
 func (r *QueryRequest[T]) Consistent() *QueryRequest[T] {
 	r.consistent = true
 	return r
 }
 
+// Select fetches only the fields returned by the selectors and the fields
+// stored in the key of the table and of the index read. Other fields are left
+// at their zero value.
+func (r *QueryRequest[T]) Select(selectors ...func(*T) any) *QueryRequest[T] {
+	r.selectors = append(r.selectors, selectors...)
+	return r
+}
+
+func (r *QueryRequest[T]) In(db *DB) *QueryRequest[T] {
+	r.db = db
+	return r
+}
+
+func (r *QueryRequest[T]) Stats(s *Stats) *QueryRequest[T] {
+	r.stats = s
+	return r
+}
+
 func (r *QueryRequest[T]) Exec() ([]*T, error) {
-	if r.betweenStart != nil {
-		return r.execBetween()
-	}
-	return r.execBeginsWith()
+	return r.ExecContext(context.Background())
 }
 
-func (r *QueryRequest[T]) execBeginsWith() ([]*T, error) {
+func (r *QueryRequest[T]) ExecContext(ctx context.Context) ([]*T, error) {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb query: %w", err)
 	}
-	pkVal, err := valueOf(r.item)
+	db, err := resolve(r.db)
 	if err != nil {
 		return nil, wrap(err)
 	}
-	if r.beginsWith == nil {
-		r.beginsWith = new(T)
+	ctx, done := db.track(ctx, r.operation, r.stats)
+	defer done()
+	input, err := r.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	var result []*T
+	if scan, ok := input.(*dynamodb.ScanInput); ok && r.segments > 0 {
+		result, err = r.execParallel(ctx, db, scan)
+	} else {
+		result, err = r.exec(ctx, db, input)
 	}
-	pkType := pkVal.Type()
-	pkitem, err := makeItem(pkType, pkVal, func(attr string) bool {
-		return strings.HasSuffix(attr, "PK") || strings.HasSuffix(attr, "GSI")
-	})
 	if err != nil {
 		return nil, wrap(err)
 	}
-	index, err := r.chooseIndex(pkitem, pkVal, pkType)
+	return result, nil
+}
+
+func (r *QueryRequest[T]) All() iter.Seq2[*T, error] {
+	return r.Iter(context.Background())
+}
+
+// Count returns the number of items that match the query and the number of
+// items read to find them, without loading the items. Page, Select and
+// Parallel are ignored.
+func (r *QueryRequest[T]) Count() (int, int, error) {
+	return r.CountContext(context.Background())
+}
+
+func (r *QueryRequest[T]) CountContext(ctx context.Context) (int, int, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb query: %w", err)
+	}
+	db, err := resolve(r.db)
 	if err != nil {
-		return nil, wrap(err)
+		return 0, 0, wrap(err)
 	}
-	if index == pkType.Name()+"GSI" {
-		result, err := r.scan(index)
+	ctx, done := db.track(ctx, r.operation, r.stats)
+	defer done()
+	q := *r
+	q.limit, q.offset, q.selectors, q.segments = 0, nil, nil, 0
+	input, err := q.build(db)
+	if err != nil {
+		return 0, 0, wrap(invalid(err))
+	}
+	switch input := input.(type) {
+	case *dynamodb.QueryInput:
+		if q.after != nil || q.before != nil {
+			// The range is checked on the sort keys of the items, so
+			// they are read instead of only counted.
+			input.ProjectionExpression = aws.String("#sk")
+			break
+		}
+		input.Select = types.SelectCount
+	case *dynamodb.ScanInput:
+		input.Select = types.SelectCount
+	}
+	var count, scanned int
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, wrap(err)
+		}
+		page, err := q.next(ctx, db, input)
 		if err != nil {
-			return nil, wrap(err)
+			return 0, 0, wrap(err)
+		}
+		count += page.count
+		scanned += page.scanned
+		if page.lek == nil {
+			return count, scanned, nil
+		}
+	}
+}
+
+// Iter returns the items one at a time, reading pages only as they are
+// needed. With Page, iteration starts at offset, pages read at most maxSize
+// items, and offset is set to resume after the last item returned when
+// iteration stops, or to an empty string after the last item.
+func (r *QueryRequest[T]) Iter(ctx context.Context) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		wrap := func(err error) error {
+			return fmt.Errorf("goddb query: %w", err)
+		}
+		db, err := resolve(r.db)
+		if err != nil {
+			yield(nil, wrap(err))
+			return
+		}
+		ctx, done := db.track(ctx, r.operation, r.stats)
+		defer done()
+		input, err := r.build(db)
+		if err != nil {
+			yield(nil, wrap(invalid(err)))
+			return
+		}
+		if scan, ok := input.(*dynamodb.ScanInput); ok && r.segments > 0 {
+			if err := r.iterParallel(ctx, db, scan, yield); err != nil {
+				yield(nil, wrap(err))
+			}
+			return
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, wrap(err))
+				return
+			}
+			page, err := r.next(ctx, db, input)
+			if err != nil {
+				yield(nil, wrap(err))
+				return
+			}
+			vals, err := loadValues[T](db, page.items)
+			if err != nil {
+				yield(nil, wrap(err))
+				return
+			}
+			for i, val := range vals {
+				if r.offset != nil {
+					key := page.lek
+					if key == nil || i < len(vals)-1 {
+						key = itemKey(db, input, page.items[i])
+					}
+					if err := r.setOffset(key); err != nil {
+						yield(nil, wrap(err))
+						return
+					}
+				}
+				if !yield(val, nil) {
+					return
+				}
+			}
+			if page.lek == nil {
+				if err := r.setOffset(nil); err != nil {
+					yield(nil, wrap(err))
+				}
+				return
+			}
 		}
-		return result, nil
 	}
-	skval, err := valueOf(r.beginsWith)
+}
+
+func (r *QueryRequest[T]) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb query: %w", err)
+	}
+	db, err := resolve(r.db)
 	if err != nil {
 		return nil, wrap(err)
 	}
-	skitem, err := makeItem(skval.Type(), skval, func(attr string) bool {
-		return strings.HasSuffix(attr, "SK")
+	input, err := r.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: r.operation, Input: input}, nil
+}
+
+// build returns the input of the first page, which is a *dynamodb.ScanInput
+// if the index chosen has to be scanned and a *dynamodb.QueryInput
+// otherwise.
+func (r *QueryRequest[T]) build(db *DB) (any, error) {
+	var conditions int
+	for _, set := range []bool{r.beginsWith != nil, r.betweenStart != nil, r.after != nil || r.before != nil} {
+		if set {
+			conditions++
+		}
+	}
+	if conditions > 1 {
+		return nil, errors.New("only one of BeginsWith, Between and After or Before can be used")
+	}
+	pkVal, err := valueOf(r.item)
+	if err != nil {
+		return nil, err
+	}
+	pkType := pkVal.Type()
+	pkitem, err := makeItem(pkType, pkVal, db.tagChar, func(attr string) bool {
+		return strings.HasSuffix(attr, "PK") || strings.HasSuffix(attr, "GSI")
 	})
 	if err != nil {
-		return nil, wrap(err)
+		return nil, err
+	}
+	index, err := r.chooseIndex(pkitem, pkVal, pkType, db.tagChar)
+	if err != nil {
+		return nil, err
+	}
+	simple := index == pkType.Name()+"GSI"
+	scan := simple && !indexSet(pkVal, pkType, index)
+	if simple && conditions > 0 {
+		return nil, fmt.Errorf("index %s has no sort key for BeginsWith, Between, After or Before", index)
+	}
+	if r.segments > 0 && !scan {
+		return nil, errors.New("parallel reads are only supported on scanned indexes")
+	}
+	var lek map[string]types.AttributeValue
+	if r.offset != nil && r.segments == 0 {
+		lek, err = offsetToLastEvaluatedKey(*r.offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var proj *string
+	var projNames map[string]string
+	if len(r.selectors) > 0 {
+		// The key of the index is projected too, so the offset of a page
+		// can be made from any item read.
+		var keys []string
+		switch {
+		case simple:
+			keys = []string{index}
+		case index != "":
+			keys = []string{index + "PK", index + "SK"}
+		}
+		exp, names, err := projection(db, r.selectors, keys...)
+		if err != nil {
+			return nil, err
+		}
+		proj, projNames = &exp, names
+	}
+	if scan {
+		if r.desc {
+			return nil, fmt.Errorf("descending order is not supported on index %s", index)
+		}
+		input := &dynamodb.ScanInput{
+			TableName:                aws.String(db.tableName),
+			IndexName:                &index,
+			ExclusiveStartKey:        lek,
+			ProjectionExpression:     proj,
+			ExpressionAttributeNames: projNames,
+		}
+		if r.limit > 0 {
+			input.Limit = aws.Int32(int32(r.limit))
+		}
+		if r.consistent {
+			input.ConsistentRead = aws.Bool(true)
+		}
+		if r.segments > 0 {
+			input.TotalSegments = aws.Int32(int32(r.segments))
+		}
+		if err := r.where(&input.FilterExpression, &input.ExpressionAttributeNames, &input.ExpressionAttributeValues); err != nil {
+			return nil, err
+		}
+		return input, nil
+	}
+	names := map[string]string{
+		"#pk": db.attributeName(index + "PK"),
+		"#sk": db.attributeName(index + "SK"),
+	}
+	if simple {
+		names = map[string]string{"#pk": index}
 	}
 	input := &dynamodb.QueryInput{
-		TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
+		TableName:                 aws.String(db.tableName),
+		ExclusiveStartKey:         lek,
+		ExpressionAttributeNames:  merge(names, projNames),
+		ExpressionAttributeValues: make(map[string]types.AttributeValue),
+		ProjectionExpression:      proj,
 	}
 	if r.consistent {
 		input.ConsistentRead = aws.Bool(true)
 	}
+	if r.desc {
+		input.ScanIndexForward = aws.Bool(false)
+	}
 	if r.limit > 0 {
 		input.Limit = aws.Int32(int32(r.limit))
 	}
 	if index != "" {
 		input.IndexName = &index
 	}
-	if input.ExpressionAttributeNames == nil {
-		input.ExpressionAttributeNames = make(map[string]string)
+	pkattr := index + "PK"
+	if simple {
+		pkattr = index
 	}
-	input.ExpressionAttributeNames["#pk"] = index + "PK"
-	input.ExpressionAttributeNames["#sk"] = index + "SK"
-	pkattrval, ok := pkitem[index+"PK"]
+	pkattrval, ok := pkitem[pkattr]
 	if !ok {
-		return nil, wrap(fmt.Errorf("could not get hash key from index %s", index))
-	}
-	skattrval, ok := skitem[index+"SK"]
-	if !ok {
-		return nil, wrap(errors.New("could not get range key"))
+		return nil, fmt.Errorf("could not get hash key from index %s", index)
 	}
 	pkmember, ok := pkattrval.(*types.AttributeValueMemberS)
 	if !ok {
-		return nil, wrap(errors.New("hash attribute value not of type string"))
-	}
-	skmember, ok := skattrval.(*types.AttributeValueMemberS)
-	if !ok {
-		return nil, wrap(errors.New("range attribute value not of type string"))
-	}
-	if input.ExpressionAttributeValues == nil {
-		input.ExpressionAttributeValues = make(map[string]types.AttributeValue)
+		return nil, errors.New("hash attribute value not of type string")
 	}
 	input.ExpressionAttributeValues[":pk"] = &types.AttributeValueMemberS{Value: pkmember.Value}
-	input.ExpressionAttributeValues[":sk"] = &types.AttributeValueMemberS{Value: skmember.Value}
-	input.KeyConditionExpression = aws.String("#pk = :pk and begins_with(#sk, :sk)")
-	result, err := r.exec(input)
-	if err != nil {
-		return nil, wrap(err)
+	if err := r.where(&input.FilterExpression, &input.ExpressionAttributeNames, &input.ExpressionAttributeValues); err != nil {
+		return nil, err
 	}
-	return result, nil
-}
-
-func (r *QueryRequest[T]) exec(input *dynamodb.QueryInput) ([]*T, error) {
-	var lek map[string]types.AttributeValue
-	if r.offset != nil {
-		var err error
-		lek, err = offsetToLastEvaluatedKey(*r.offset)
-		if err != nil {
-			return nil, err
-		}
+	if simple {
+		// The index has no sort key, so only its partition is read.
+		input.KeyConditionExpression = aws.String("#pk = :pk")
+		return input, nil
 	}
-	var result []*T
-	ctx := context.Background()
-	for {
-		input.ExclusiveStartKey = lek
-		output, err := client.Query(ctx, input)
+	if r.betweenStart != nil {
+		start, err := r.sortKey(db, r.betweenStart, index)
 		if err != nil {
 			return nil, err
 		}
-		lek = output.LastEvaluatedKey
-		vals, err := loadValues[T](output.Items)
+		end, err := r.sortKey(db, r.betweenEnd, index)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, vals...)
-		if lek == nil {
-			if r.offset != nil {
-				*r.offset = ""
+		input.ExpressionAttributeValues[":start"] = &types.AttributeValueMemberS{Value: start}
+		input.ExpressionAttributeValues[":end"] = &types.AttributeValueMemberS{Value: end}
+		input.KeyConditionExpression = aws.String("#pk = :pk and #sk between :start and :end")
+		return input, nil
+	}
+	if r.after != nil || r.before != nil {
+		var start, end string
+		var err error
+		if r.after != nil {
+			if start, err = r.sortKey(db, r.after, index); err != nil {
+				return nil, err
 			}
-			break
+			input.ExpressionAttributeValues[":start"] = &types.AttributeValueMemberS{Value: start}
 		}
-		if r.offset != nil {
-			*r.offset, err = lastEvaluatedKeyToOffset(lek)
-			if err != nil {
+		if r.before != nil {
+			if end, err = r.sortKey(db, r.before, index); err != nil {
 				return nil, err
 			}
+			input.ExpressionAttributeValues[":end"] = &types.AttributeValueMemberS{Value: end}
 		}
-		if input.Limit != nil {
-			break
+		switch {
+		case r.after != nil && r.before != nil:
+			input.KeyConditionExpression = aws.String("#pk = :pk and #sk between :start and :end")
+		case r.after != nil && r.afterEqual:
+			input.KeyConditionExpression = aws.String("#pk = :pk and #sk >= :start")
+		case r.after != nil:
+			input.KeyConditionExpression = aws.String("#pk = :pk and #sk > :start")
+		case r.beforeEqual:
+			input.KeyConditionExpression = aws.String("#pk = :pk and #sk <= :end")
+		default:
+			input.KeyConditionExpression = aws.String("#pk = :pk and #sk < :end")
 		}
+		return input, nil
 	}
-	return result, nil
+	beginsWith := r.beginsWith
+	if beginsWith == nil {
+		beginsWith = new(T)
+	}
+	sk, err := r.sortKey(db, beginsWith, index)
+	if err != nil {
+		return nil, err
+	}
+	input.ExpressionAttributeValues[":sk"] = &types.AttributeValueMemberS{Value: sk}
+	input.KeyConditionExpression = aws.String("#pk = :pk and begins_with(#sk, :sk)")
+	return input, nil
 }
 
-func (r *QueryRequest[T]) scan(index string) ([]*T, error) {
-	var lek map[string]types.AttributeValue
-	if r.offset != nil {
-		var err error
-		lek, err = offsetToLastEvaluatedKey(*r.offset)
-		if err != nil {
-			return nil, err
+// where sets the filter expression of the input from the filter of r.
+func (r *QueryRequest[T]) where(filter **string, names *map[string]string, values *map[string]types.AttributeValue) error {
+	if r.filter == nil {
+		return nil
+	}
+	exp, ns, vs, err := r.filter.expression(len(*values))
+	if err != nil {
+		return err
+	}
+	*filter = &exp
+	*names = merge(*names, ns)
+	*values = merge(*values, vs)
+	return nil
+}
+
+// inRange drops the items of a query with After or Before that are not of T
+// or are not in the range. A single bound also matches the sort keys of other
+// types in the partition, and between also matches the bounds of After and
+// Before, so the range is checked as its own condition after the items are
+// read. It reports whether the items read went past the sort keys of T, in
+// which case no more can be in the range.
+func (r *QueryRequest[T]) inRange(db *DB, input *dynamodb.QueryInput, items []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, bool) {
+	prefix := reflect.TypeFor[T]().Name() + string(db.tagChar)
+	sortKey := func(name string) string {
+		if v, ok := input.ExpressionAttributeValues[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
 		}
+		return ""
 	}
-	var result []*T
-	ctx := context.Background()
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
-		IndexName: &index,
+	start, end := sortKey(":start"), sortKey(":end")
+	var kept []map[string]types.AttributeValue
+	for _, item := range items {
+		v, _ := item[input.ExpressionAttributeNames["#sk"]].(*types.AttributeValueMemberS)
+		if v == nil {
+			continue
+		}
+		sk := v.Value
+		if !strings.HasPrefix(sk, prefix) {
+			if r.desc == (sk < prefix) {
+				return kept, true
+			}
+			continue
+		}
+		if r.after != nil && r.before != nil && (!r.afterEqual && sk == start || !r.beforeEqual && sk == end) {
+			continue
+		}
+		kept = append(kept, item)
 	}
-	if r.limit > 0 {
-		input.Limit = aws.Int32(int32(r.limit))
+	return kept, false
+}
+
+// sortKey returns the value of the sort key of the index for v.
+func (r *QueryRequest[T]) sortKey(db *DB, v *T, index string) (string, error) {
+	val, err := valueOf(v)
+	if err != nil {
+		return "", err
 	}
-	if r.consistent {
-		input.ConsistentRead = aws.Bool(true)
+	item, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool {
+		return strings.HasSuffix(attr, "SK")
+	})
+	if err != nil {
+		return "", err
 	}
+	attrval, ok := item[index+"SK"]
+	if !ok {
+		return "", errors.New("could not get range key")
+	}
+	member, ok := attrval.(*types.AttributeValueMemberS)
+	if !ok {
+		return "", errors.New("range attribute value not of type string")
+	}
+	return member.Value, nil
+}
+
+func (r *QueryRequest[T]) exec(ctx context.Context, db *DB, input any) ([]*T, error) {
+	var result []*T
 	for {
-		input.ExclusiveStartKey = lek
-		output, err := client.Scan(ctx, input)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := r.next(ctx, db, input)
 		if err != nil {
 			return nil, err
 		}
-		lek = output.LastEvaluatedKey
-		vals, err := loadValues[T](output.Items)
+		vals, err := loadValues[T](db, page.items)
 		if err != nil {
 			return nil, err
 		}
 		result = append(result, vals...)
-		if lek == nil {
-			if r.offset != nil {
-				*r.offset = ""
-			}
+		if err := r.setOffset(page.lek); err != nil {
+			return nil, err
+		}
+		if page.lek == nil || r.limit > 0 && len(result) >= r.limit {
 			break
 		}
-		if r.offset != nil {
-			*r.offset, err = lastEvaluatedKeyToOffset(lek)
-			if err != nil {
-				return nil, err
+		// A page can come back short because of the filter or the size
+		// limit of a response, so the rest is read from the next one.
+		if r.limit > 0 {
+			setLimit(input, r.limit-len(result))
+		}
+	}
+	return result, nil
+}
+
+// page is a page of items read by a query or scan.
+type page struct {
+	items   []map[string]types.AttributeValue
+	lek     map[string]types.AttributeValue
+	count   int
+	scanned int
+}
+
+// next reads the page of input and moves input on to the page after it. The
+// last evaluated key of the page is nil after the last page.
+func (r *QueryRequest[T]) next(ctx context.Context, db *DB, input any) (*page, error) {
+	switch input := input.(type) {
+	case *dynamodb.QueryInput:
+		output, err := send[dynamodb.QueryOutput](ctx, db, r.operation, r.item, input)
+		if err != nil {
+			return nil, err
+		}
+		p := &page{output.Items, output.LastEvaluatedKey, int(output.Count), int(output.ScannedCount)}
+		if r.after != nil || r.before != nil {
+			var past bool
+			p.items, past = r.inRange(db, input, p.items)
+			if input.Select != types.SelectCount {
+				p.count = len(p.items)
+			}
+			if past {
+				p.lek = nil
 			}
 		}
-		if input.Limit != nil {
-			break
+		input.ExclusiveStartKey = p.lek
+		return p, nil
+	case *dynamodb.ScanInput:
+		output, err := send[dynamodb.ScanOutput](ctx, db, r.operation, r.item, input)
+		if err != nil {
+			return nil, err
 		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+		return &page{output.Items, output.LastEvaluatedKey, int(output.Count), int(output.ScannedCount)}, nil
 	}
-	return result, nil
+	return nil, fmt.Errorf("unsupported input %T", input)
+}
+
+func setLimit(input any, limit int) {
+	switch input := input.(type) {
+	case *dynamodb.QueryInput:
+		input.Limit = aws.Int32(int32(limit))
+	case *dynamodb.ScanInput:
+		input.Limit = aws.Int32(int32(limit))
+	}
+}
+
+// itemKey returns the key of item in the table or index read by input. A
+// page that starts after item has it as its exclusive start key.
+func itemKey(db *DB, input any, item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	attrs := []string{db.pkName, db.skName}
+	switch input := input.(type) {
+	case *dynamodb.QueryInput:
+		if input.IndexName != nil {
+			attrs = append(attrs, input.ExpressionAttributeNames["#pk"])
+			if sk, ok := input.ExpressionAttributeNames["#sk"]; ok {
+				attrs = append(attrs, sk)
+			}
+		}
+	case *dynamodb.ScanInput:
+		if input.IndexName != nil {
+			attrs = append(attrs, *input.IndexName)
+		}
+	}
+	key := make(map[string]types.AttributeValue, len(attrs))
+	for _, attr := range attrs {
+		if v, ok := item[attr]; ok {
+			key[attr] = v
+		}
+	}
+	return key
 }
 
-func (r *QueryRequest[T]) chooseIndex(item map[string]types.AttributeValue, val reflect.Value, ty reflect.Type) (string, error) {
+// setOffset stores the offset to resume from after lek, or an empty offset
+// if there are no more pages.
+func (r *QueryRequest[T]) setOffset(lek map[string]types.AttributeValue) error {
+	if r.offset == nil {
+		return nil
+	}
+	if lek == nil {
+		*r.offset = ""
+		return nil
+	}
+	offset, err := lastEvaluatedKeyToOffset(lek)
+	if err != nil {
+		return err
+	}
+	*r.offset = offset
+	return nil
+}
+
+// indexSet reports whether all fields of the index are set on val, so the
+// index can be queried instead of scanned.
+func indexSet(val reflect.Value, ty reflect.Type, index string) bool {
+	for i := 0; i < ty.NumField(); i++ {
+		ft := ty.Field(i)
+		if ft.IsExported() && slices.Contains(strings.Split(ft.Tag.Get("goddb"), ","), index) && val.Field(i).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *QueryRequest[T]) chooseIndex(item map[string]types.AttributeValue, val reflect.Value, ty reflect.Type, tagChar rune) (string, error) {
 	attrToFields := make(map[string][]string)
 	for i := 0; i < ty.NumField(); i++ {
 		ft := ty.Field(i)
@@ -260,7 +720,7 @@ func (r *QueryRequest[T]) chooseIndex(item map[string]types.AttributeValue, val
 			if !ok {
 				return "", errors.New("hash attribute not string")
 			}
-			if strings.HasSuffix(member.Value, string(TagChar)) {
+			if strings.HasSuffix(member.Value, string(tagChar)) {
 				continue
 			}
 			var foundZeroValue bool
@@ -273,7 +733,7 @@ func (r *QueryRequest[T]) chooseIndex(item map[string]types.AttributeValue, val
 			if foundZeroValue {
 				continue
 			}
-			pks[strings.TrimSuffix(attrName, "PK")] = len(strings.Split(member.Value, string(TagChar)))
+			pks[strings.TrimSuffix(attrName, "PK")] = len(strings.Split(member.Value, string(tagChar)))
 		}
 		if attrName == ty.Name()+"GSI" {
 			gsi = attrName
@@ -300,103 +760,3 @@ func (r *QueryRequest[T]) chooseIndex(item map[string]types.AttributeValue, val
 	}
 	return maxPKIndexes[0], nil
 }
-
-func (r *QueryRequest[T]) execBetween() ([]*T, error) {
-	wrap := func(err error) error {
-		return fmt.Errorf("goddb query: %w", err)
-	}
-	pkval, err := valueOf(r.item)
-	if err != nil {
-		return nil, wrap(err)
-	}
-	if r.beginsWith == nil {
-		r.beginsWith = new(T)
-	}
-	startval, err := valueOf(r.betweenStart)
-	if err != nil {
-		return nil, wrap(err)
-	}
-	endval, err := valueOf(r.betweenEnd)
-	if err != nil {
-		return nil, wrap(err)
-	}
-	pkType := pkval.Type()
-	pkitem, err := makeItem(pkType, pkval, func(attr string) bool {
-		return strings.HasSuffix(attr, "PK") || strings.HasSuffix(attr, "GSI")
-	})
-	if err != nil {
-		return nil, wrap(err)
-	}
-	index, err := r.chooseIndex(pkitem, pkval, pkType)
-	if err != nil {
-		return nil, wrap(err)
-	}
-	if index == pkType.Name()+"GSI" {
-		result, err := r.scan(index)
-		if err != nil {
-			return nil, wrap(err)
-		}
-		return result, nil
-	}
-	startItem, err := makeItem(startval.Type(), startval, func(attr string) bool {
-		return strings.HasSuffix(attr, "SK")
-	})
-	if err != nil {
-		return nil, wrap(err)
-	}
-	endItem, err := makeItem(endval.Type(), endval, func(attr string) bool {
-		return strings.HasSuffix(attr, "SK")
-	})
-	if err != nil {
-		return nil, wrap(err)
-	}
-	input := &dynamodb.QueryInput{
-		TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
-	}
-	if r.limit > 0 {
-		input.Limit = aws.Int32(int32(r.limit))
-	}
-	if r.consistent {
-		input.ConsistentRead = aws.Bool(true)
-	}
-	if index != "" {
-		input.IndexName = &index
-	}
-	if input.ExpressionAttributeNames == nil {
-		input.ExpressionAttributeNames = make(map[string]string)
-	}
-	input.ExpressionAttributeNames["#pk"] = index + "PK"
-	input.ExpressionAttributeNames["#sk"] = index + "SK"
-	pkattrval, ok := pkitem[index+"PK"]
-	if !ok {
-		return nil, wrap(errors.New("could not get hash key"))
-	}
-	startAttrVal, ok := startItem[index+"SK"]
-	if !ok {
-		return nil, wrap(errors.New("could not get range key"))
-	}
-	endAttrVal, ok := endItem[index+"SK"]
-	if !ok {
-		return nil, wrap(errors.New("could not get range key"))
-	}
-	pkmember, ok := pkattrval.(*types.AttributeValueMemberS)
-	if !ok {
-		return nil, wrap(errors.New("hash attribute value not of type string"))
-	}
-	startMember, ok := startAttrVal.(*types.AttributeValueMemberS)
-	if !ok {
-		return nil, wrap(errors.New("range attribute value not of type string"))
-	}
-	endMember, ok := endAttrVal.(*types.AttributeValueMemberS)
-	if !ok {
-		return nil, wrap(errors.New("range attribute value not of type string"))
-	}
-	if input.ExpressionAttributeValues == nil {
-		input.ExpressionAttributeValues = make(map[string]types.AttributeValue)
-	}
-	input.ExpressionAttributeValues[":pk"] = &types.AttributeValueMemberS{Value: pkmember.Value}
-	input.ExpressionAttributeValues[":start"] = &types.AttributeValueMemberS{Value: startMember.Value}
-	input.ExpressionAttributeValues[":end"] = &types.AttributeValueMemberS{Value: endMember.Value}
-	input.KeyConditionExpression = aws.String("#pk = :pk and #sk between :start and :end")
-	return r.exec(input)
-}
@@ -0,0 +1,151 @@
+package goddb
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// RetryPolicy controls how calls to DynamoDB that fail are retried by goddb.
+// It applies on top of any retries made by the client itself.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including
+	// the first one.
+	MaxAttempts int
+	// BaseDelay and MaxDelay bound the exponential backoff between attempts.
+	// They default to 25ms and 2s.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Retryable reports whether a failed request can be retried. It
+	// defaults to Retryable. Conditional check failures are never retried.
+	Retryable func(req *Request, err error) bool
+}
+
+// WithRetryPolicy sets the retry policy of the DB. By default calls are not
+// retried.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(db *DB) {
+		db.retry = p
+	}
+}
+
+var retryableCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"ThrottlingException":                    true,
+	"RequestLimitExceeded":                   true,
+	"TransactionConflictException":           true,
+	"TransactionInProgressException":         true,
+}
+
+var retryableReasons = map[string]bool{
+	"None":                          true,
+	"TransactionConflict":           true,
+	"ThrottlingError":               true,
+	"ProvisionedThroughputExceeded": true,
+}
+
+// Retryable reports whether err means the request was not applied because
+// of throttling or a conflicting transaction. Internal server errors are
+// also retryable for requests that are safe to repeat, which are reads and
+// writes without a condition.
+func Retryable(req *Request, err error) bool {
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if !retryableReasons[aws.ToString(reason.Code)] {
+				return false
+			}
+		}
+		return true
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if retryableCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	return apiErr.ErrorCode() == "InternalServerError" && repeatable(req)
+}
+
+func repeatable(req *Request) bool {
+	switch input := req.Input.(type) {
+	case *dynamodb.GetItemInput, *dynamodb.QueryInput, *dynamodb.ScanInput:
+		return true
+	case *dynamodb.PutItemInput:
+		return input.ConditionExpression == nil
+	case *dynamodb.DeleteItemInput:
+		return input.ConditionExpression == nil
+	case *dynamodb.TransactWriteItemsInput:
+		return input.ClientRequestToken != nil
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(req *Request, err error) bool {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(req, err)
+	}
+	return Retryable(req, err)
+}
+
+// backoff returns a random delay of up to BaseDelay * 2^attempt, capped at
+// MaxDelay.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, limit := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 25 * time.Millisecond
+	}
+	if limit <= 0 {
+		limit = 2 * time.Second
+	}
+	delay := limit
+	if attempt < 32 && base<<attempt > 0 && base<<attempt < limit {
+		delay = base << attempt
+	}
+	return rand.N(delay) + 1
+}
+
+// do calls the handler of db with req, retrying as allowed by the policy.
+func (p *RetryPolicy) do(ctx context.Context, db *DB, req *Request) (any, error) {
+	for attempt := 0; ; attempt++ {
+		out, err := db.handle(ctx, req)
+		if err == nil || attempt+1 >= p.MaxAttempts || !p.retryable(req, err) {
+			return out, err
+		}
+		if p.wait(ctx, attempt) != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait sleeps for the backoff of the given attempt, or until ctx is done.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// minBatchAttempts is the least number of attempts made to process the
+// unprocessed keys and items of batch calls, which DynamoDB may return
+// under normal load.
+const minBatchAttempts = 8
+
+func (p *RetryPolicy) batchAttempts() int {
+	return max(p.MaxAttempts, minBatchAttempts)
+}
@@ -0,0 +1,134 @@
+package goddb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Parallel splits a scan of a <Struct>GSI index into segments that are read
+// at the same time. With Page, pages have at most maxSize items from all
+// segments together and the offset keeps the position of every segment, so
+// the scan can be resumed with the same number of segments.
+func (r *QueryRequest[T]) Parallel(segments int) *QueryRequest[T] {
+	r.segments = segments
+	return r
+}
+
+// execParallel reads the segments until the page is full, or to the end
+// without Page.
+func (r *QueryRequest[T]) execParallel(ctx context.Context, db *DB, input *dynamodb.ScanInput) ([]*T, error) {
+	var result []*T
+	err := r.iterParallel(ctx, db, input, func(val *T, _ error) bool {
+		result = append(result, val)
+		return r.limit == 0 || len(result) < r.limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// iterParallel yields the items of all segments in the order their pages
+// arrive. It returns nil without yielding again if yield returns false.
+func (r *QueryRequest[T]) iterParallel(ctx context.Context, db *DB, input *dynamodb.ScanInput, yield func(*T, error) bool) error {
+	var offset string
+	if r.offset != nil {
+		offset = *r.offset
+	}
+	leks, done, err := segmentsToLastEvaluatedKeys(offset, r.segments)
+	if err != nil {
+		return invalid(err)
+	}
+	type segmentPage struct {
+		segment int
+		page    *page
+		err     error
+	}
+	pages := make(chan segmentPage)
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	var remaining int
+	for segment := range r.segments {
+		if done[segment] {
+			continue
+		}
+		remaining++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			input := r.segment(input, segment, leks[segment])
+			for {
+				page, err := r.next(ctx, db, input)
+				select {
+				case pages <- segmentPage{segment, page, err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil || page.lek == nil {
+					return
+				}
+			}
+		}()
+	}
+	for remaining > 0 {
+		var p segmentPage
+		select {
+		case p = <-pages:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if p.err != nil {
+			return p.err
+		}
+		vals, err := loadValues[T](db, p.page.items)
+		if err != nil {
+			return err
+		}
+		for i, val := range vals {
+			if r.offset != nil {
+				if i < len(vals)-1 {
+					leks[p.segment] = itemKey(db, input, p.page.items[i])
+				} else {
+					leks[p.segment], done[p.segment] = p.page.lek, p.page.lek == nil
+				}
+				if err := r.setSegmentOffsets(leks, done); err != nil {
+					return err
+				}
+			}
+			if !yield(val, nil) {
+				return nil
+			}
+		}
+		leks[p.segment], done[p.segment] = p.page.lek, p.page.lek == nil
+		if p.page.lek == nil {
+			remaining--
+		}
+	}
+	return r.setSegmentOffsets(leks, done)
+}
+
+// segment returns a copy of input that reads the segment from lek.
+func (r *QueryRequest[T]) segment(input *dynamodb.ScanInput, segment int, lek map[string]types.AttributeValue) *dynamodb.ScanInput {
+	in := *input
+	in.Segment = aws.Int32(int32(segment))
+	in.ExclusiveStartKey = lek
+	return &in
+}
+
+func (r *QueryRequest[T]) setSegmentOffsets(leks []map[string]types.AttributeValue, done []bool) error {
+	if r.offset == nil {
+		return nil
+	}
+	offset, err := lastEvaluatedKeysToSegments(leks, done)
+	if err != nil {
+		return err
+	}
+	*r.offset = offset
+	return nil
+}
@@ -1,11 +1,26 @@
 package goddb
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 )
 
 func getFieldNameFromTest[T any](test func(*T) any) string {
+	ft, ok := getFieldFromTest(test)
+	if !ok {
+		return ""
+	}
+	if tag := ft.Tag.Get("goddb"); tag != "" {
+		attrs := strings.Split(tag, ",")
+		return attrs[0]
+	}
+	return ft.Name
+}
+
+// getFieldFromTest returns the field of T returned by test.
+func getFieldFromTest[T any](test func(*T) any) (reflect.StructField, bool) {
 	input := new(T)
 	v := reflect.ValueOf(input).Elem()
 	t := v.Type()
@@ -37,12 +52,39 @@ func getFieldNameFromTest[T any](test func(*T) any) string {
 		}
 		output := test(input)
 		if !reflect.ValueOf(output).IsZero() {
-			if tag := ft.Tag.Get("goddb"); tag != "" {
-				attrs := strings.Split(tag, ",")
-				return attrs[0]
-			}
-			return ft.Name
+			return ft, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// projection returns a ProjectionExpression of the fields returned by the
+// selectors and the attribute names it uses. The key attributes and keys are
+// always projected, as are the attributes tagged fields are stored in.
+func projection[T any](db *DB, selectors []func(*T) any, keys ...string) (string, map[string]string, error) {
+	attrs := append([]string{"PK", "SK"}, keys...)
+	for _, selector := range selectors {
+		ft, ok := getFieldFromTest(selector)
+		if !ok {
+			return "", nil, errors.New("selected field not found")
+		}
+		if tag := ft.Tag.Get("goddb"); tag != "" {
+			attrs = append(attrs, strings.Split(tag, ",")...)
+		} else {
+			attrs = append(attrs, ft.Name)
+		}
+	}
+	names := make(map[string]string)
+	var exp []string
+	seen := make(map[string]bool)
+	for _, attr := range attrs {
+		if seen[attr] {
+			continue
 		}
+		seen[attr] = true
+		name := fmt.Sprintf("#p%d", len(exp))
+		names[name] = db.attributeName(attr)
+		exp = append(exp, name)
 	}
-	return ""
+	return strings.Join(exp, ", "), names, nil
 }
@@ -0,0 +1,182 @@
+package goddb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Stats describes the work done by DynamoDB to execute a request.
+type Stats struct {
+	Operation Operation
+	// Pages is the number of calls made to DynamoDB, one per page for
+	// queries and scans.
+	Pages int
+	// ScannedCount and Count are the number of items evaluated and returned
+	// by queries and scans.
+	ScannedCount int
+	Count        int
+	Capacity     []Capacity
+	Duration     time.Duration
+}
+
+// Capacity is the capacity consumed on a table, or on one of its indexes if
+// Index is not empty.
+type Capacity struct {
+	Table string
+	Index string
+	Read  float64
+	Write float64
+}
+
+// WithStats calls fn with the stats of every request executed with the DB.
+func WithStats(fn func(ctx context.Context, stats *Stats)) Option {
+	return func(db *DB) {
+		db.stats = fn
+	}
+}
+
+type statsKey struct{}
+
+// tracker collects the stats of an operation, which may make calls
+// concurrently.
+type tracker struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func (t *tracker) record(output any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.record(output)
+}
+
+// track starts collecting stats for an operation if they are wanted by dst
+// or the DB. Calls made by operations started with the returned context are
+// counted towards the same stats. done must be called when the operation
+// finishes.
+func (db *DB) track(ctx context.Context, op Operation, dst *Stats) (context.Context, func()) {
+	if _, ok := ctx.Value(statsKey{}).(*tracker); ok || dst == nil && db.stats == nil {
+		return ctx, func() {}
+	}
+	t := &tracker{stats: Stats{Operation: op}}
+	start := time.Now()
+	return context.WithValue(ctx, statsKey{}, t), func() {
+		stats := &t.stats
+		stats.Duration = time.Since(start)
+		if dst != nil {
+			*dst = *stats
+		}
+		if db.stats != nil {
+			db.stats(ctx, stats)
+		}
+	}
+}
+
+func requestCapacity(input any) {
+	switch input := input.(type) {
+	case *dynamodb.GetItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.PutItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.UpdateItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.DeleteItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.QueryInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.ScanInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.BatchGetItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.BatchWriteItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.TransactGetItemsInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	case *dynamodb.TransactWriteItemsInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	}
+}
+
+func (s *Stats) record(output any) {
+	s.Pages++
+	switch output := output.(type) {
+	case *dynamodb.GetItemOutput:
+		s.consume(output.ConsumedCapacity, false)
+	case *dynamodb.PutItemOutput:
+		s.consume(output.ConsumedCapacity, true)
+	case *dynamodb.UpdateItemOutput:
+		s.consume(output.ConsumedCapacity, true)
+	case *dynamodb.DeleteItemOutput:
+		s.consume(output.ConsumedCapacity, true)
+	case *dynamodb.QueryOutput:
+		s.ScannedCount += int(output.ScannedCount)
+		s.Count += int(output.Count)
+		s.consume(output.ConsumedCapacity, false)
+	case *dynamodb.ScanOutput:
+		s.ScannedCount += int(output.ScannedCount)
+		s.Count += int(output.Count)
+		s.consume(output.ConsumedCapacity, false)
+	case *dynamodb.BatchGetItemOutput:
+		for i := range output.ConsumedCapacity {
+			s.consume(&output.ConsumedCapacity[i], false)
+		}
+	case *dynamodb.BatchWriteItemOutput:
+		for i := range output.ConsumedCapacity {
+			s.consume(&output.ConsumedCapacity[i], true)
+		}
+	case *dynamodb.TransactGetItemsOutput:
+		for i := range output.ConsumedCapacity {
+			s.consume(&output.ConsumedCapacity[i], false)
+		}
+	case *dynamodb.TransactWriteItemsOutput:
+		for i := range output.ConsumedCapacity {
+			s.consume(&output.ConsumedCapacity[i], true)
+		}
+	}
+}
+
+func (s *Stats) consume(c *types.ConsumedCapacity, write bool) {
+	if c == nil {
+		return
+	}
+	table := aws.ToString(c.TableName)
+	switch {
+	case c.Table != nil:
+		s.add(table, "", c.Table.CapacityUnits, c.Table.ReadCapacityUnits, c.Table.WriteCapacityUnits, write)
+	case c.GlobalSecondaryIndexes == nil && c.LocalSecondaryIndexes == nil:
+		s.add(table, "", c.CapacityUnits, c.ReadCapacityUnits, c.WriteCapacityUnits, write)
+	}
+	for index, units := range c.GlobalSecondaryIndexes {
+		s.add(table, index, units.CapacityUnits, units.ReadCapacityUnits, units.WriteCapacityUnits, write)
+	}
+	for index, units := range c.LocalSecondaryIndexes {
+		s.add(table, index, units.CapacityUnits, units.ReadCapacityUnits, units.WriteCapacityUnits, write)
+	}
+}
+
+func (s *Stats) add(table string, index string, total, read, write *float64, isWrite bool) {
+	r, w := aws.ToFloat64(read), aws.ToFloat64(write)
+	if read == nil && write == nil {
+		if isWrite {
+			w = aws.ToFloat64(total)
+		} else {
+			r = aws.ToFloat64(total)
+		}
+	}
+	if r == 0 && w == 0 {
+		return
+	}
+	for i := range s.Capacity {
+		if s.Capacity[i].Table == table && s.Capacity[i].Index == index {
+			s.Capacity[i].Read += r
+			s.Capacity[i].Write += w
+			return
+		}
+	}
+	s.Capacity = append(s.Capacity, Capacity{Table: table, Index: index, Read: r, Write: w})
+}
@@ -0,0 +1,123 @@
+package goddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxTransactionItems = 100
+
+type TransactionGetRequest struct {
+	db     *DB
+	stats  *Stats
+	values []any
+}
+
+// TransactionGet loads items of any type at the same point in time into the
+// values given with Get. If some are not found, Exec returns a
+// *NotFoundError listing them.
+func TransactionGet() *TransactionGetRequest {
+	return &TransactionGetRequest{}
+}
+
+func (t *TransactionGetRequest) Get(value any) *TransactionGetRequest {
+	t.values = append(t.values, value)
+	return t
+}
+
+func (t *TransactionGetRequest) In(db *DB) *TransactionGetRequest {
+	t.db = db
+	return t
+}
+
+func (t *TransactionGetRequest) Stats(s *Stats) *TransactionGetRequest {
+	t.stats = s
+	return t
+}
+
+func (t *TransactionGetRequest) Exec() error {
+	return t.ExecContext(context.Background())
+}
+
+func (t *TransactionGetRequest) ExecContext(ctx context.Context) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb transaction get items: %w", err)
+	}
+	db, err := resolve(t.db)
+	if err != nil {
+		return wrap(err)
+	}
+	ctx, done := db.track(ctx, OperationTransactionGet, t.stats)
+	defer done()
+	input, err := t.build(db)
+	if err != nil {
+		return wrap(invalid(err))
+	}
+	output, err := send[dynamodb.TransactGetItemsOutput](ctx, db, OperationTransactionGet, t.values, input)
+	if err != nil {
+		return wrap(err)
+	}
+	var missing []any
+	for i, value := range t.values {
+		if i >= len(output.Responses) || len(output.Responses[i].Item) == 0 {
+			missing = append(missing, value)
+			continue
+		}
+		val, err := valueOf(value)
+		if err != nil {
+			return wrap(err)
+		}
+		if err := setFieldValues(val, db.fromTable(output.Responses[i].Item), db.tagChar); err != nil {
+			return wrap(err)
+		}
+	}
+	if len(missing) > 0 {
+		return wrap(&NotFoundError{Values: missing})
+	}
+	return nil
+}
+
+func (t *TransactionGetRequest) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb transaction get items: %w", err)
+	}
+	db, err := resolve(t.db)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	input, err := t.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: OperationTransactionGet, Input: input}, nil
+}
+
+func (t *TransactionGetRequest) build(db *DB) (*dynamodb.TransactGetItemsInput, error) {
+	if len(t.values) > maxTransactionItems {
+		return nil, fmt.Errorf("at most %d items can be read in a transaction", maxTransactionItems)
+	}
+	var items []types.TransactGetItem
+	for _, value := range t.values {
+		if reflect.ValueOf(value).Kind() != reflect.Pointer {
+			return nil, errors.New("must be pointer to struct")
+		}
+		val, err := valueOf(value)
+		if err != nil {
+			return nil, err
+		}
+		key, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, types.TransactGetItem{
+			Get: &types.Get{Key: db.toTable(key), TableName: aws.String(db.tableName)},
+		})
+	}
+	return &dynamodb.TransactGetItemsInput{TransactItems: items}, nil
+}
@@ -2,17 +2,68 @@ package goddb
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
+	"reflect"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+const maxTokenLength = 36
+
+// TransactionCanceledError is returned by TransactionWrite when DynamoDB
+// cancels the transaction. Reasons has an entry for each item, in the order
+// the items were added.
+type TransactionCanceledError struct {
+	Reasons []CancellationReason
+	err     error
+}
+
+type CancellationReason struct {
+	Value any
+	// Code is the reason the item failed, such as ConditionalCheckFailed or
+	// TransactionConflict, or None if it did not.
+	Code    string
+	Message string
+	// Old is the existing item, decoded into a new value of the type of
+	// Value, if its condition failed and ReturnOldOnConditionFailure was
+	// used.
+	Old any
+}
+
+func (e *TransactionCanceledError) Error() string {
+	codes := make([]string, len(e.Reasons))
+	for i, reason := range e.Reasons {
+		codes[i] = reason.Code
+	}
+	return fmt.Sprintf("%s [%s]", ErrTransactionCanceled, strings.Join(codes, ", "))
+}
+
+func (e *TransactionCanceledError) Unwrap() error {
+	return e.err
+}
+
 type TransactionWriteRequest struct {
-	puts    []any
-	deletes []any
+	db        *DB
+	stats     *Stats
+	items     []TransactionItem
+	returnOld bool
+	token     *string
+}
+
+// TransactionItem is a write that can be made in a transaction with Write.
+// It is implemented by *PutRequest, *UpdateRequest, *DeleteRequest and
+// *CheckRequest. Their conditions and ReturnOldOnConditionFailure apply in
+// the transaction; other return values are ignored.
+type TransactionItem interface {
+	transactionValue() any
+	transactionItem(db *DB) (*types.TransactWriteItem, error)
+	transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error)
 }
 
 func TransactionWrite() *TransactionWriteRequest {
@@ -20,58 +71,305 @@ func TransactionWrite() *TransactionWriteRequest {
 }
 
 func (t *TransactionWriteRequest) Put(value any) *TransactionWriteRequest {
-	t.puts = append(t.puts, value)
+	t.items = append(t.items, transactionPut{value})
 	return t
 }
 
 func (t *TransactionWriteRequest) Delete(value any) *TransactionWriteRequest {
-	t.deletes = append(t.deletes, value)
+	t.items = append(t.items, transactionDelete{value})
+	return t
+}
+
+// Write adds puts, updates, deletes and condition checks built with Put,
+// Update, Delete and Check to the transaction. Items are written to the DB of
+// the transaction, so an item bound to another DB with In is an error. Stats
+// of items are ignored.
+func (t *TransactionWriteRequest) Write(items ...TransactionItem) *TransactionWriteRequest {
+	t.items = append(t.items, items...)
+	return t
+}
+
+// sameDB returns an error if an item bound to own is written in a
+// transaction of db.
+func sameDB(own *DB, db *DB) error {
+	if own != nil && own != db {
+		return errors.New("item is bound to a different DB than the transaction")
+	}
+	return nil
+}
+
+// ReturnOldOnConditionFailure loads the existing items whose conditions fail
+// into the reasons of the TransactionCanceledError.
+func (t *TransactionWriteRequest) ReturnOldOnConditionFailure() *TransactionWriteRequest {
+	t.returnOld = true
+	return t
+}
+
+// Idempotent sets the client request token of the transaction. Repeating a
+// transaction that succeeded with the same token within ten minutes does not
+// apply its writes again. Repeating it with different writes fails with
+// ErrIdempotentParameterMismatch. Tokens have at most 36 characters.
+func (t *TransactionWriteRequest) Idempotent(token string) *TransactionWriteRequest {
+	t.token = &token
+	return t
+}
+
+// IdempotentFor makes the transaction idempotent with a token derived from
+// requestID, which can be of any length.
+func (t *TransactionWriteRequest) IdempotentFor(requestID string) *TransactionWriteRequest {
+	sum := sha256.Sum256([]byte(requestID))
+	return t.Idempotent(hex.EncodeToString(sum[:16]))
+}
+
+func (t *TransactionWriteRequest) In(db *DB) *TransactionWriteRequest {
+	t.db = db
+	return t
+}
+
+func (t *TransactionWriteRequest) Stats(s *Stats) *TransactionWriteRequest {
+	t.stats = s
 	return t
 }
 
 func (t *TransactionWriteRequest) Exec() error {
+	return t.ExecContext(context.Background())
+}
+
+func (t *TransactionWriteRequest) ExecContext(ctx context.Context) error {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb transaction write items: %w", err)
 	}
-	var items []types.TransactWriteItem
-	for _, put := range t.puts {
-		val, err := valueOf(put)
-		if err != nil {
+	db, err := resolve(t.db)
+	if err != nil {
+		return wrap(err)
+	}
+	ctx, done := db.track(ctx, OperationTransactionWrite, t.stats)
+	defer done()
+	input, err := t.build(db)
+	if err != nil {
+		return wrap(invalid(err))
+	}
+	values := make([]any, len(t.items))
+	for i, item := range t.items {
+		values[i] = item.transactionValue()
+	}
+	if _, err := send[dynamodb.TransactWriteItemsOutput](ctx, db, OperationTransactionWrite, values, input); err != nil {
+		var canceled *types.TransactionCanceledException
+		if !errors.As(err, &canceled) {
 			return wrap(err)
 		}
-		ty := val.Type()
-		item, err := makeItem(ty, val, func(attr string) bool { return true })
-		if err != nil {
-			return wrap(err)
+		e := &TransactionCanceledError{err: err}
+		// An old item that cannot be decoded is left nil, and its error is
+		// joined to the cancellation so the reasons are not lost.
+		errs := []error{e}
+		for i, reason := range canceled.CancellationReasons {
+			r := CancellationReason{Code: aws.ToString(reason.Code), Message: aws.ToString(reason.Message)}
+			if i < len(values) {
+				r.Value = values[i]
+				if len(reason.Item) > 0 {
+					old, err := t.items[i].transactionOld(db, reason.Item)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("decode old item %d: %w", i, err))
+					} else {
+						r.Old = old
+					}
+				}
+			}
+			e.Reasons = append(e.Reasons, r)
 		}
-		if err := validateCompleteKey(ty, val); err != nil {
-			return wrap(err)
+		if len(errs) > 1 {
+			return wrap(errors.Join(errs...))
 		}
-		items = append(items, types.TransactWriteItem{
-			Put: &types.Put{Item: item, TableName: aws.String(os.Getenv("GODDB_TABLE_NAME"))},
-		})
+		return wrap(e)
 	}
-	for _, del := range t.deletes {
-		val, err := valueOf(del)
-		if err != nil {
-			return wrap(err)
-		}
-		ty := val.Type()
-		item, err := makeItem(ty, val, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	return nil
+}
+
+func (t *TransactionWriteRequest) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb transaction write items: %w", err)
+	}
+	db, err := resolve(t.db)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	input, err := t.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: OperationTransactionWrite, Input: input}, nil
+}
+
+func (t *TransactionWriteRequest) build(db *DB) (*dynamodb.TransactWriteItemsInput, error) {
+	if len(t.items) == 0 || len(t.items) > maxTransactionItems {
+		return nil, fmt.Errorf("1 to %d items can be written in a transaction", maxTransactionItems)
+	}
+	var items []types.TransactWriteItem
+	for _, item := range t.items {
+		i, err := item.transactionItem(db)
 		if err != nil {
-			return wrap(err)
+			return nil, err
 		}
-		if err := validateCompleteKey(ty, val); err != nil {
-			return wrap(err)
+		if t.returnOld {
+			returnOld := types.ReturnValuesOnConditionCheckFailureAllOld
+			switch {
+			case i.Put != nil:
+				i.Put.ReturnValuesOnConditionCheckFailure = returnOld
+			case i.Delete != nil:
+				i.Delete.ReturnValuesOnConditionCheckFailure = returnOld
+			case i.Update != nil:
+				i.Update.ReturnValuesOnConditionCheckFailure = returnOld
+			case i.ConditionCheck != nil:
+				i.ConditionCheck.ReturnValuesOnConditionCheckFailure = returnOld
+			}
 		}
-		items = append(items, types.TransactWriteItem{
-			Delete: &types.Delete{Key: item, TableName: aws.String(os.Getenv("GODDB_TABLE_NAME"))},
-		})
+		items = append(items, *i)
 	}
-	if _, err := client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
-		TransactItems: items,
-	}); err != nil {
-		return wrap(err)
+	if t.token != nil && (len(*t.token) < 1 || len(*t.token) > maxTokenLength) {
+		return nil, fmt.Errorf("idempotency token must have 1 to %d characters", maxTokenLength)
 	}
-	return nil
+	return &dynamodb.TransactWriteItemsInput{TransactItems: items, ClientRequestToken: t.token}, nil
+}
+
+type transactionPut struct {
+	value any
+}
+
+func (p transactionPut) transactionValue() any {
+	return p.value
+}
+
+func (p transactionPut) transactionItem(db *DB) (*types.TransactWriteItem, error) {
+	val, err := valueOf(p.value)
+	if err != nil {
+		return nil, err
+	}
+	ty := val.Type()
+	item, err := makeItem(ty, val, db.tagChar, func(attr string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCompleteKey(ty, val); err != nil {
+		return nil, err
+	}
+	return &types.TransactWriteItem{
+		Put: &types.Put{Item: db.toTable(item), TableName: aws.String(db.tableName)},
+	}, nil
+}
+
+func (p transactionPut) transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error) {
+	return decodeNew(db, p.value, attrs)
+}
+
+type transactionDelete struct {
+	value any
+}
+
+func (d transactionDelete) transactionValue() any {
+	return d.value
+}
+
+func (d transactionDelete) transactionItem(db *DB) (*types.TransactWriteItem, error) {
+	val, err := valueOf(d.value)
+	if err != nil {
+		return nil, err
+	}
+	ty := val.Type()
+	item, err := makeItem(ty, val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCompleteKey(ty, val); err != nil {
+		return nil, err
+	}
+	return &types.TransactWriteItem{
+		Delete: &types.Delete{Key: db.toTable(item), TableName: aws.String(db.tableName)},
+	}, nil
+}
+
+func (d transactionDelete) transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error) {
+	return decodeNew(db, d.value, attrs)
+}
+
+// decodeNew decodes attrs into a new value of the struct type of value.
+func decodeNew(db *DB, value any, attrs map[string]types.AttributeValue) (any, error) {
+	val, err := valueOf(value)
+	if err != nil {
+		return nil, err
+	}
+	old := reflect.New(val.Type())
+	if err := setFieldValues(old.Elem(), db.fromTable(attrs), db.tagChar); err != nil {
+		return nil, err
+	}
+	return old.Interface(), nil
+}
+
+// decodeOld decodes attrs into dst, or into a new value if dst is nil.
+func decodeOld[T any](db *DB, dst *T, attrs map[string]types.AttributeValue) (any, error) {
+	if dst == nil {
+		dst = new(T)
+	}
+	if err := decode(db, dst, attrs); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+type CheckRequest[T any] struct {
+	value     *T
+	condition *Condition[T]
+	onFailure *T
+}
+
+// Check makes a transaction fail unless the item with the key of value meets
+// the condition. The item is not written.
+func Check[T any](value *T, condition *Condition[T]) *CheckRequest[T] {
+	return &CheckRequest[T]{
+		value:     value,
+		condition: condition,
+	}
+}
+
+// ReturnOldOnConditionFailure loads the existing item into v if the
+// condition fails.
+func (r *CheckRequest[T]) ReturnOldOnConditionFailure(v *T) *CheckRequest[T] {
+	r.onFailure = v
+	return r
+}
+
+func (r *CheckRequest[T]) transactionValue() any {
+	return r.value
+}
+
+func (r *CheckRequest[T]) transactionItem(db *DB) (*types.TransactWriteItem, error) {
+	if r.condition == nil {
+		return nil, errors.New("check requires a condition")
+	}
+	val, err := valueOf(r.value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	if err != nil {
+		return nil, err
+	}
+	exp, names, values, err := r.condition.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	check := &types.ConditionCheck{
+		Key:                       db.toTable(key),
+		TableName:                 aws.String(db.tableName),
+		ConditionExpression:       &exp,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+	if r.onFailure != nil {
+		check.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	}
+	return &types.TransactWriteItem{ConditionCheck: check}, nil
+}
+
+func (r *CheckRequest[T]) transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error) {
+	return decodeOld(db, r.onFailure, attrs)
 }
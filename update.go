@@ -2,8 +2,8 @@ package goddb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"reflect"
 	"strings"
 
@@ -13,6 +13,8 @@ import (
 )
 
 type UpdateRequest[T any] struct {
+	db        *DB
+	stats     *Stats
 	item      *T
 	sets      []*T
 	adds      []*T
@@ -20,14 +22,14 @@ type UpdateRequest[T any] struct {
 	removes   []func(*T) any
 	input     *dynamodb.UpdateItemInput
 	condition *Condition[T]
+	returned  *T
+	onFailure *T
 }
 
 func Update[T any](item *T) *UpdateRequest[T] {
 	return &UpdateRequest[T]{
-		input: &dynamodb.UpdateItemInput{
-			TableName: aws.String(os.Getenv("GODDB_TABLE_NAME")),
-		},
-		item: item,
+		input: &dynamodb.UpdateItemInput{},
+		item:  item,
 	}
 }
 
@@ -56,49 +58,168 @@ func (r *UpdateRequest[T]) If(condition *Condition[T]) *UpdateRequest[T] {
 	return r
 }
 
+// ReturnNew loads the item as it is after the update into v.
+func (r *UpdateRequest[T]) ReturnNew(v *T) *UpdateRequest[T] {
+	return r.returnValues(types.ReturnValueAllNew, v)
+}
+
+// ReturnOld loads the item as it was before the update into v, if it
+// existed.
+func (r *UpdateRequest[T]) ReturnOld(v *T) *UpdateRequest[T] {
+	return r.returnValues(types.ReturnValueAllOld, v)
+}
+
+// ReturnUpdatedNew loads only the updated fields, as they are after the
+// update, into v.
+func (r *UpdateRequest[T]) ReturnUpdatedNew(v *T) *UpdateRequest[T] {
+	return r.returnValues(types.ReturnValueUpdatedNew, v)
+}
+
+// ReturnUpdatedOld loads only the updated fields, as they were before the
+// update, into v.
+func (r *UpdateRequest[T]) ReturnUpdatedOld(v *T) *UpdateRequest[T] {
+	return r.returnValues(types.ReturnValueUpdatedOld, v)
+}
+
+func (r *UpdateRequest[T]) returnValues(rv types.ReturnValue, v *T) *UpdateRequest[T] {
+	r.input.ReturnValues = rv
+	r.returned = v
+	return r
+}
+
+// ReturnOldOnConditionFailure loads the existing item into v if the
+// condition fails.
+func (r *UpdateRequest[T]) ReturnOldOnConditionFailure(v *T) *UpdateRequest[T] {
+	r.input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	r.onFailure = v
+	return r
+}
+
+func (r *UpdateRequest[T]) In(db *DB) *UpdateRequest[T] {
+	r.db = db
+	return r
+}
+
+func (r *UpdateRequest[T]) Stats(s *Stats) *UpdateRequest[T] {
+	r.stats = s
+	return r
+}
+
 func (r *UpdateRequest[T]) Exec() error {
+	return r.ExecContext(context.Background())
+}
+
+func (r *UpdateRequest[T]) ExecContext(ctx context.Context) error {
 	wrap := func(err error) error {
 		return fmt.Errorf("goddb update: %w", err)
 	}
-	val, err := valueOf(r.item)
+	db, err := resolve(r.db)
 	if err != nil {
 		return wrap(err)
 	}
-	r.input.Key, err = makeItem(val.Type(), val, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	ctx, done := db.track(ctx, OperationUpdate, r.stats)
+	defer done()
+	input, err := r.build(db)
 	if err != nil {
-		return wrap(err)
+		return wrap(invalid(err))
 	}
-	var exp strings.Builder
-	if err := r.updateExpressionSet(&exp); err != nil {
+	output, err := send[dynamodb.UpdateItemOutput](ctx, db, OperationUpdate, r.item, input)
+	if err != nil {
+		var ex *types.ConditionalCheckFailedException
+		if errors.As(err, &ex) {
+			if err := decode(db, r.onFailure, ex.Item); err != nil {
+				return wrap(err)
+			}
+		}
 		return wrap(err)
 	}
-	if err := r.updateExpressionAdd(&exp); err != nil {
+	if err := decode(db, r.returned, output.Attributes); err != nil {
 		return wrap(err)
 	}
-	if err := r.updateExpressionDelete(&exp); err != nil {
-		return wrap(err)
+	return nil
+}
+
+func (r *UpdateRequest[T]) Explain() (*Explanation, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("goddb update: %w", err)
 	}
-	if err := r.updateExpressionRemove(&exp); err != nil {
-		return wrap(err)
+	db, err := resolve(r.db)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	input, err := r.build(db)
+	if err != nil {
+		return nil, wrap(invalid(err))
+	}
+	return &Explanation{Operation: OperationUpdate, Input: input}, nil
+}
+
+func (r *UpdateRequest[T]) build(db *DB) (*dynamodb.UpdateItemInput, error) {
+	val, err := valueOf(r.item)
+	if err != nil {
+		return nil, err
+	}
+	key, err := makeItem(val.Type(), val, db.tagChar, func(attr string) bool { return attr == "SK" || attr == "PK" })
+	if err != nil {
+		return nil, err
 	}
-	r.input.UpdateExpression = aws.String(exp.String())
+	input := *r.input
+	input.TableName = aws.String(db.tableName)
+	input.Key = db.toTable(key)
+	var exp strings.Builder
+	if err := r.updateExpressionSet(&input, &exp); err != nil {
+		return nil, err
+	}
+	if err := r.updateExpressionAdd(&input, &exp); err != nil {
+		return nil, err
+	}
+	if err := r.updateExpressionDelete(&input, &exp); err != nil {
+		return nil, err
+	}
+	if err := r.updateExpressionRemove(&input, &exp); err != nil {
+		return nil, err
+	}
+	input.UpdateExpression = aws.String(exp.String())
 	if r.condition != nil {
-		exp, names, values, err := r.condition.expression(len(r.input.ExpressionAttributeValues))
+		exp, names, values, err := r.condition.expression(len(input.ExpressionAttributeValues))
 		if err != nil {
-			return wrap(err)
+			return nil, err
 		}
-		r.input.ConditionExpression = &exp
-		r.input.ExpressionAttributeNames = merge(r.input.ExpressionAttributeNames, names)
-		r.input.ExpressionAttributeValues = merge(r.input.ExpressionAttributeValues, values)
+		input.ConditionExpression = &exp
+		input.ExpressionAttributeNames = merge(input.ExpressionAttributeNames, names)
+		input.ExpressionAttributeValues = merge(input.ExpressionAttributeValues, values)
+	}
+	return &input, nil
+}
+
+func (r *UpdateRequest[T]) transactionValue() any {
+	return r.item
+}
+
+func (r *UpdateRequest[T]) transactionItem(db *DB) (*types.TransactWriteItem, error) {
+	if err := sameDB(r.db, db); err != nil {
+		return nil, err
 	}
-	_, err = client.UpdateItem(context.Background(), r.input)
+	input, err := r.build(db)
 	if err != nil {
-		return wrap(err)
+		return nil, err
 	}
-	return nil
+	return &types.TransactWriteItem{Update: &types.Update{
+		Key:                                 input.Key,
+		TableName:                           input.TableName,
+		UpdateExpression:                    input.UpdateExpression,
+		ConditionExpression:                 input.ConditionExpression,
+		ExpressionAttributeNames:            input.ExpressionAttributeNames,
+		ExpressionAttributeValues:           input.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+	}}, nil
+}
+
+func (r *UpdateRequest[T]) transactionOld(db *DB, attrs map[string]types.AttributeValue) (any, error) {
+	return decodeOld(db, r.onFailure, attrs)
 }
 
-func (r *UpdateRequest[T]) updateExpressionSet(exp *strings.Builder) error {
+func (r *UpdateRequest[T]) updateExpressionSet(input *dynamodb.UpdateItemInput, exp *strings.Builder) error {
 	if len(r.sets) > 0 {
 		if exp.Len() > 0 {
 			exp.WriteRune(' ')
@@ -121,14 +242,14 @@ func (r *UpdateRequest[T]) updateExpressionSet(exp *strings.Builder) error {
 			if fv.IsZero() {
 				continue
 			}
-			expAttrVal, err := r.getExpressionAttributeValue(fv)
+			expAttrVal, err := r.getExpressionAttributeValue(input, fv)
 			if err != nil {
 				return err
 			}
 			if expAttrVal == "" {
 				continue
 			}
-			attrName := r.getExpressionAttributeName(ft.Name)
+			attrName := r.getExpressionAttributeName(input, ft.Name)
 			if hit {
 				exp.WriteString(", ")
 			}
@@ -141,17 +262,17 @@ func (r *UpdateRequest[T]) updateExpressionSet(exp *strings.Builder) error {
 	return nil
 }
 
-func (r *UpdateRequest[T]) getExpressionAttributeName(name string) string {
-	if r.input.ExpressionAttributeNames == nil {
-		r.input.ExpressionAttributeNames = make(map[string]string)
+func (r *UpdateRequest[T]) getExpressionAttributeName(input *dynamodb.UpdateItemInput, name string) string {
+	if input.ExpressionAttributeNames == nil {
+		input.ExpressionAttributeNames = make(map[string]string)
 	}
 	attrName := fmt.Sprintf("#%s", name)
-	r.input.ExpressionAttributeNames[attrName] = name
+	input.ExpressionAttributeNames[attrName] = name
 	return attrName
 }
 
 // getExpressionAttributeValue can return "", nil
-func (r *UpdateRequest[T]) getExpressionAttributeValue(value reflect.Value) (string, error) {
+func (r *UpdateRequest[T]) getExpressionAttributeValue(input *dynamodb.UpdateItemInput, value reflect.Value) (string, error) {
 	av, err := makeAttributeValue(value)
 	if err != nil {
 		return "", err
@@ -159,15 +280,15 @@ func (r *UpdateRequest[T]) getExpressionAttributeValue(value reflect.Value) (str
 	if av == nil {
 		return "", nil
 	}
-	if r.input.ExpressionAttributeValues == nil {
-		r.input.ExpressionAttributeValues = make(map[string]types.AttributeValue)
+	if input.ExpressionAttributeValues == nil {
+		input.ExpressionAttributeValues = make(map[string]types.AttributeValue)
 	}
-	attrValue := fmt.Sprintf(":%d", len(r.input.ExpressionAttributeValues))
-	r.input.ExpressionAttributeValues[attrValue] = av
+	attrValue := fmt.Sprintf(":%d", len(input.ExpressionAttributeValues))
+	input.ExpressionAttributeValues[attrValue] = av
 	return attrValue, nil
 }
 
-func (r *UpdateRequest[T]) updateExpressionAdd(exp *strings.Builder) error {
+func (r *UpdateRequest[T]) updateExpressionAdd(input *dynamodb.UpdateItemInput, exp *strings.Builder) error {
 	if len(r.adds) > 0 {
 		if exp.Len() > 0 {
 			exp.WriteRune(' ')
@@ -190,7 +311,7 @@ func (r *UpdateRequest[T]) updateExpressionAdd(exp *strings.Builder) error {
 			if fv.IsZero() {
 				continue
 			}
-			expAttrVal, err := r.getExpressionAttributeValue(fv)
+			expAttrVal, err := r.getExpressionAttributeValue(input, fv)
 			if err != nil {
 				return err
 			}
@@ -201,7 +322,7 @@ func (r *UpdateRequest[T]) updateExpressionAdd(exp *strings.Builder) error {
 				exp.WriteString(", ")
 			}
 			hit = true
-			attrName := r.getExpressionAttributeName(ft.Name)
+			attrName := r.getExpressionAttributeName(input, ft.Name)
 			exp.WriteString(attrName)
 			exp.WriteString(" ")
 			exp.WriteString(expAttrVal)
@@ -210,7 +331,7 @@ func (r *UpdateRequest[T]) updateExpressionAdd(exp *strings.Builder) error {
 	return nil
 }
 
-func (r *UpdateRequest[T]) updateExpressionDelete(exp *strings.Builder) error {
+func (r *UpdateRequest[T]) updateExpressionDelete(input *dynamodb.UpdateItemInput, exp *strings.Builder) error {
 	if len(r.deletes) > 0 {
 		if exp.Len() > 0 {
 			exp.WriteRune(' ')
@@ -233,7 +354,7 @@ func (r *UpdateRequest[T]) updateExpressionDelete(exp *strings.Builder) error {
 			if fv.IsZero() {
 				continue
 			}
-			expAttrVal, err := r.getExpressionAttributeValue(fv)
+			expAttrVal, err := r.getExpressionAttributeValue(input, fv)
 			if err != nil {
 				return err
 			}
@@ -244,7 +365,7 @@ func (r *UpdateRequest[T]) updateExpressionDelete(exp *strings.Builder) error {
 				exp.WriteString(", ")
 			}
 			hit = true
-			attrName := r.getExpressionAttributeName(ft.Name)
+			attrName := r.getExpressionAttributeName(input, ft.Name)
 			exp.WriteString(attrName)
 			exp.WriteString(" ")
 			exp.WriteString(expAttrVal)
@@ -253,7 +374,7 @@ func (r *UpdateRequest[T]) updateExpressionDelete(exp *strings.Builder) error {
 	return nil
 }
 
-func (r *UpdateRequest[T]) updateExpressionRemove(exp *strings.Builder) error {
+func (r *UpdateRequest[T]) updateExpressionRemove(input *dynamodb.UpdateItemInput, exp *strings.Builder) error {
 	if len(r.removes) > 0 {
 		if exp.Len() > 0 {
 			exp.WriteRune(' ')
@@ -265,7 +386,7 @@ func (r *UpdateRequest[T]) updateExpressionRemove(exp *strings.Builder) error {
 			exp.WriteString(", ")
 		}
 		fieldName := getFieldNameFromTest(remove)
-		attrName := r.getExpressionAttributeName(fieldName)
+		attrName := r.getExpressionAttributeName(input, fieldName)
 		exp.WriteString(attrName)
 	}
 	return nil
@@ -2,12 +2,14 @@ package goddb
 
 import (
 	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -140,24 +142,24 @@ func makeSliceAttributeValue(v reflect.Value) (types.AttributeValue, error) {
 	return av, nil
 }
 
-func taggedAttributeValue(ps []tagValuePair) (types.AttributeValue, error) {
+func taggedAttributeValue(ps []tagValuePair, tagChar rune) (types.AttributeValue, error) {
 	slices.SortFunc(ps, func(a, b tagValuePair) int { return cmp.Compare(a.tag, b.tag) })
 	var b strings.Builder
 	for i := range ps {
 		tag := ps[i].tag
 		value := ps[i].value
 		if b.Len() > 0 {
-			b.WriteRune(TagChar)
+			b.WriteRune(tagChar)
 		}
 		if tag != "" {
 			b.WriteString(tag)
-			b.WriteRune(TagChar)
+			b.WriteRune(tagChar)
 		}
 		switch value.Kind() {
 		case reflect.String:
 			str := value.String()
-			if strings.Contains(str, string(TagChar)) {
-				return nil, fmt.Errorf("indexed values can not contain tag char %s", string(TagChar))
+			if strings.Contains(str, string(tagChar)) {
+				return nil, fmt.Errorf("indexed values can not contain tag char %s", string(tagChar))
 			}
 			b.WriteString(str)
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -186,7 +188,7 @@ func formatFloat(f float64) string {
 	return strconv.FormatFloat(f, 'f', -1, 64)
 }
 
-func makeItem(ty reflect.Type, val reflect.Value, filter func(string) bool) (map[string]types.AttributeValue, error) {
+func makeItem(ty reflect.Type, val reflect.Value, tagChar rune, filter func(string) bool) (map[string]types.AttributeValue, error) {
 	tagged := make(map[string][]tagValuePair)
 	plain := make(map[string]int)
 	for i := 0; i < ty.NumField(); i++ {
@@ -239,7 +241,7 @@ func makeItem(ty reflect.Type, val reflect.Value, filter func(string) bool) (map
 		item[k] = av
 	}
 	for k, v := range tagged {
-		av, err := taggedAttributeValue(v)
+		av, err := taggedAttributeValue(v, tagChar)
 		if err != nil {
 			return nil, err
 		}
@@ -273,9 +275,10 @@ func validateCompleteKey(ty reflect.Type, val reflect.Value) error {
 	return nil
 }
 
-func setFieldValues(val reflect.Value, item map[string]types.AttributeValue) error {
+func setFieldValues(val reflect.Value, item map[string]types.AttributeValue, tagChar rune) error {
 	ty := val.Type()
 	var skFieldVal reflect.Value
+	gsiFieldVals := make(map[string][]reflect.Value)
 	for i := 0; i < ty.NumField(); i++ {
 		f := ty.Field(i)
 		if !f.IsExported() {
@@ -283,19 +286,37 @@ func setFieldValues(val reflect.Value, item map[string]types.AttributeValue) err
 		}
 		tag := f.Tag.Get("goddb")
 		attrs := strings.Split(tag, ",")
-		if slices.Contains(attrs, "SK") {
+		if slices.Contains(attrs, "SK") && !skFieldVal.IsValid() {
 			skFieldVal = val.Field(i)
-			break
+		}
+		for _, attr := range attrs {
+			if strings.HasSuffix(attr, "GSI") {
+				gsiFieldVals[attr] = append(gsiFieldVals[attr], val.Field(i))
+			}
 		}
 	}
 	for attrName, attrVal := range item {
 		av := item[attrName]
+		if fieldVals, ok := gsiFieldVals[attrName]; ok {
+			s, ok := av.(*types.AttributeValueMemberS)
+			if !ok {
+				return fmt.Errorf("attribute %s should be string", attrName)
+			}
+			parts := strings.Split(s.Value, string(tagChar))
+			if len(parts) != len(fieldVals) {
+				continue
+			}
+			for i, fieldVal := range fieldVals {
+				setFieldValFromVal(fieldVal, parts[i])
+			}
+			continue
+		}
 		if strings.HasSuffix(attrName, "PK") {
 			s, ok := av.(*types.AttributeValueMemberS)
 			if !ok {
 				return fmt.Errorf("attribute %s should be string", attrName)
 			}
-			parts := strings.Split(s.Value, string(TagChar))
+			parts := strings.Split(s.Value, string(tagChar))
 			for i := 0; i < len(parts)/2; i++ {
 				tag := parts[i*2]
 				v := parts[i*2+1]
@@ -309,7 +330,7 @@ func setFieldValues(val reflect.Value, item map[string]types.AttributeValue) err
 			if !ok {
 				return fmt.Errorf("attribute %s should be string", attrName)
 			}
-			parts := strings.Split(s.Value, string(TagChar))
+			parts := strings.Split(s.Value, string(tagChar))
 			v := parts[1]
 			setFieldValFromVal(skFieldVal, v)
 			continue
@@ -523,7 +544,7 @@ func setFieldValFromVal(fieldVal reflect.Value, v string) {
 	}
 }
 
-func loadValues[T any](items []map[string]types.AttributeValue) ([]*T, error) {
+func loadValues[T any](db *DB, items []map[string]types.AttributeValue) ([]*T, error) {
 	result := make([]*T, len(items))
 	for i, item := range items {
 		t := new(T)
@@ -531,7 +552,7 @@ func loadValues[T any](items []map[string]types.AttributeValue) ([]*T, error) {
 		for val.Kind() == reflect.Pointer {
 			val = val.Elem()
 		}
-		if err := setFieldValues(val, item); err != nil {
+		if err := setFieldValues(val, db.fromTable(item), db.tagChar); err != nil {
 			return nil, err
 		}
 		result[i] = t
@@ -551,3 +572,63 @@ func merge[K comparable, V any](a, b map[K]V) map[K]V {
 	}
 	return a
 }
+
+// parallel calls fn with every chunk, at most n at a time. It stops starting
+// calls after the first error, which it returns.
+func parallel[E any](ctx context.Context, n int, chunks [][]E, fn func(ctx context.Context, chunk []E) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, max(n, 1))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+	for _, chunk := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, chunk); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// decode loads attrs returned by DynamoDB into dst, if dst is not nil.
+func decode[T any](db *DB, dst *T, attrs map[string]types.AttributeValue) error {
+	if dst == nil || len(attrs) == 0 {
+		return nil
+	}
+	val, err := valueOf(dst)
+	if err != nil {
+		return err
+	}
+	return setFieldValues(val, db.fromTable(attrs), db.tagChar)
+}
+
+// keyID identifies an item by the values of its PK and SK attributes.
+func keyID(item map[string]types.AttributeValue) string {
+	var b strings.Builder
+	for _, attr := range []string{"PK", "SK"} {
+		if member, ok := item[attr].(*types.AttributeValueMemberS); ok {
+			b.WriteString(member.Value)
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}